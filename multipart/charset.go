@@ -0,0 +1,82 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+)
+
+// charsetField is the field by which HTML forms send their charset.
+const charsetField = "_charset_"
+
+// rawValue is a form value still in its charset, which is empty if its
+// part did not give one.
+type rawValue struct {
+	name    string
+	i       int
+	charset string
+}
+
+// partCharset returns the charset parameter of the Content-Type of p.
+func partCharset(p *Part) string {
+	v := p.Header.Get("Content-Type")
+	if v == "" {
+		return ""
+	}
+	_, params, err := parseMediaType(v)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// decodeValues transcodes the values vs of f to UTF-8. Values without a
+// charset of their own are in the charset of the _charset_ field, if
+// any, or else UTF-8.
+func (f *Form) decodeValues(vs []rawValue, opts *FormOptions) error {
+	def := "utf-8"
+	if cs := f.Value[charsetField]; len(cs) > 0 && cs[0] != "" {
+		def = cs[0]
+	}
+	for _, v := range vs {
+		charset := v.charset
+		if charset == "" {
+			charset = def
+		}
+		s, err := decodeCharset(strings.ToLower(charset), f.Value[v.name][v.i], opts)
+		if err != nil {
+			return err
+		}
+		f.Value[v.name][v.i] = s
+	}
+	return nil
+}
+
+// decodeCharset converts s from charset to UTF-8. utf-8, us-ascii and
+// iso-8859-1 are handled without opts.CharsetReader; s is returned
+// unchanged if there is no reader for its charset.
+func decodeCharset(charset, s string, opts *FormOptions) (string, error) {
+	switch charset {
+	case "utf-8", "utf8", "us-ascii":
+		return s, nil
+	case "iso-8859-1", "latin1":
+		var b bytes.Buffer
+		for i := 0; i < len(s); i++ {
+			b.WriteRune(rune(s[i]))
+		}
+		return b.String(), nil
+	}
+	if opts.CharsetReader == nil {
+		return s, nil
+	}
+	r, err := opts.CharsetReader(charset, strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadAll(r)
+	return string(b), err
+}
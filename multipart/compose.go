@@ -0,0 +1,441 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cention-sany/mime"
+	"github.com/cention-sany/mime/quotedprintable"
+	"github.com/cention-sany/net/textproto"
+)
+
+// sniffLen is how much of a body is examined to pick its
+// Content-Transfer-Encoding.
+const sniffLen = 8192
+
+// maxLineLen is the line length limit of RFC 5322 section 2.1.1,
+// excluding the CRLF.
+const maxLineLen = 998
+
+// headerWidth is the width header lines are folded to, the limit of RFC
+// 2047 section 2 for lines with encoded-words.
+const headerWidth = 76
+
+// maxWordLen is the length limit of the RFC 2231 parameter sections
+// the composer writes, so that one fits on a folded line after the
+// parameter name.
+const maxWordLen = 60
+
+// An Entity is a MIME entity: a header and either a body or, when it is
+// multipart, a list of child entities.
+type Entity struct {
+	// Header holds the entity's header fields. Content-Type defaults
+	// to text/plain. If Content-Transfer-Encoding is not set, one is
+	// chosen from the content of Body when the entity is written.
+	Header textproto.MIMEHeader
+
+	// Body is the content of a non-multipart entity, not yet transfer
+	// encoded.
+	Body io.Reader
+
+	// Parts are the children of a multipart entity.
+	Parts []*Entity
+}
+
+// NewMultipartEntity returns a multipart/subtype entity holding parts.
+func NewMultipartEntity(subtype string, parts ...*Entity) *Entity {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", "multipart/"+subtype)
+	return &Entity{Header: h, Parts: parts}
+}
+
+// NewTextEntity returns a text/subtype entity holding text. Line breaks
+// are converted to CRLF and the charset is set to us-ascii or utf-8.
+func NewTextEntity(subtype, text string) *Entity {
+	charset := "us-ascii"
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 0x80 {
+			charset = "utf-8"
+			break
+		}
+	}
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "\n", "\r\n", -1)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", mime.FormatMediaType("text/"+subtype,
+		map[string]string{"charset": charset}))
+	return &Entity{Header: h, Body: strings.NewReader(text)}
+}
+
+// NewAttachment returns an entity with a Content-Disposition of
+// attachment for the named file. Non-ASCII filenames are encoded as
+// defined by RFC 2231.
+func NewAttachment(filename, contentType string, body io.Reader) *Entity {
+	return newFileEntity("attachment", filename, contentType, body)
+}
+
+// NewInlineEntity returns an entity with a Content-Disposition of inline
+// and the given Content-ID, to be referenced as "cid:contentID" from a
+// sibling in a multipart/related entity.
+func NewInlineEntity(contentID, filename, contentType string, body io.Reader) *Entity {
+	e := newFileEntity("inline", filename, contentType, body)
+	e.Header.Set("Content-Id", "<"+strings.Trim(contentID, "<>")+">")
+	return e
+}
+
+func newFileEntity(disposition, filename, contentType string, body io.Reader) *Entity {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	// the name parameter is for clients predating Content-Disposition
+	h.Set("Content-Type", formatFileName(contentType, "name", filename))
+	h.Set("Content-Disposition", formatFileName(disposition, "filename", filename))
+	return &Entity{Header: h, Body: body}
+}
+
+// formatFileName adds the parameter param holding filename to the
+// header value v. A filename that is not plain ASCII, or is too long
+// for a line, is written as an RFC 2231 extended parameter, split into
+// continuations if it is long.
+func formatFileName(v, param, filename string) string {
+	if filename == "" || mime.FormatMediaType(v, nil) == "" {
+		return v
+	}
+	if len(filename) <= maxWordLen {
+		if s := mime.FormatMediaType(v, map[string]string{param: filename}); s != "" {
+			return s
+		}
+	}
+	var sections []string
+	var b bytes.Buffer
+	b.WriteString("utf-8''")
+	for i := 0; i < len(filename); {
+		// a section holds whole UTF-8 sequences
+		_, size := utf8.DecodeRuneInString(filename[i:])
+		var enc bytes.Buffer
+		writePercent(&enc, filename[i:i+size])
+		if b.Len()+enc.Len() > maxWordLen {
+			sections = append(sections, b.String())
+			b.Reset()
+		}
+		b.Write(enc.Bytes())
+		i += size
+	}
+	sections = append(sections, b.String())
+	if len(sections) == 1 {
+		return v + "; " + param + "*=" + sections[0]
+	}
+	b.Reset()
+	b.WriteString(v)
+	for i, s := range sections {
+		fmt.Fprintf(&b, "; %s*%d*=%s", param, i, s)
+	}
+	return b.String()
+}
+
+// WriteTo writes e as a complete message to w: its header, with
+// MIME-Version added, followed by its encoded body.
+func (e *Entity) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	subtype, params, multi := e.multipart()
+	var h textproto.MIMEHeader
+	var body io.Reader
+	var mw *Writer
+	if multi {
+		mw = NewWriter(cw)
+		mw.SetHeaderFolding(headerWidth)
+		mw.SetHeaderPolicy(HeaderEncodeWords)
+		params["boundary"] = mw.Boundary()
+		h = copyHeader(e.Header)
+		h.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, params))
+		h.Del("Content-Transfer-Encoding")
+	} else {
+		h, body = e.prepare()
+	}
+	if h.Get("Mime-Version") == "" {
+		h.Set("Mime-Version", "1.0")
+	}
+	if err := writeHeader(cw, h); err != nil {
+		return cw.n, err
+	}
+	var err error
+	if multi {
+		if err = e.writeParts(mw); err == nil {
+			err = mw.Close()
+		}
+	} else {
+		err = writeEncoded(cw, h.Get("Content-Transfer-Encoding"), body, "\r\n")
+	}
+	return cw.n, err
+}
+
+// multipart reports whether e is a multipart entity, and if so returns
+// its subtype and Content-Type parameters.
+func (e *Entity) multipart() (subtype string, params map[string]string, ok bool) {
+	typ, params, err := parseMediaType(e.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(typ, "multipart/") {
+		return "", nil, false
+	}
+	return typ[len("multipart/"):], params, true
+}
+
+func copyHeader(h textproto.MIMEHeader) textproto.MIMEHeader {
+	c := make(textproto.MIMEHeader, len(h)+1)
+	for k, vv := range h {
+		c[k] = append([]string(nil), vv...)
+	}
+	return c
+}
+
+// prepare returns a copy of the header of the non-multipart entity e
+// completed with the Content-Transfer-Encoding it will be written with,
+// and the reader of the body to encode.
+func (e *Entity) prepare() (textproto.MIMEHeader, io.Reader) {
+	h := copyHeader(e.Header)
+	typ, _, err := parseMediaType(h.Get("Content-Type"))
+	if err != nil || typ == "" {
+		typ = "text/plain"
+	}
+	body := e.Body
+	if body == nil {
+		body = strings.NewReader("")
+	}
+	if h.Get("Content-Transfer-Encoding") == "" {
+		var cte string
+		cte, body = chooseTransferEncoding(typ, body)
+		h.Set("Content-Transfer-Encoding", cte)
+	}
+	return h, body
+}
+
+// writeParts writes the children of the multipart entity e to mw.
+func (e *Entity) writeParts(mw *Writer) error {
+	if len(e.Parts) == 0 {
+		return errors.New("multipart: multipart entity has no parts")
+	}
+	for _, child := range e.Parts {
+		if subtype, _, ok := child.multipart(); ok {
+			cw, err := mw.CreateMultipart(subtype, child.Header)
+			if err != nil {
+				return err
+			}
+			if err := child.writeParts(cw); err != nil {
+				return err
+			}
+			if err := cw.Close(); err != nil {
+				return err
+			}
+			continue
+		}
+		h, body := child.prepare()
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if err := writeEncoded(pw, h.Get("Content-Transfer-Encoding"), body, mw.nl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chooseTransferEncoding picks 7bit when the whole body is short enough
+// to be examined and is plain ASCII with proper CRLF lines,
+// quoted-printable for text that is mostly ASCII and base64 otherwise.
+// Message entities are never encoded, as required by RFC 2046.
+func chooseTransferEncoding(typ string, r io.Reader) (string, io.Reader) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	b, err := br.Peek(sniffLen)
+	complete := err != nil
+	if complete && is7bit(b) {
+		return "7bit", br
+	}
+	switch {
+	case strings.HasPrefix(typ, "message/"):
+		return "8bit", br
+	case strings.HasPrefix(typ, "text/") && mostlyASCII(b):
+		return "quoted-printable", br
+	}
+	return "base64", br
+}
+
+func is7bit(b []byte) bool {
+	line := 0
+	for i, c := range b {
+		switch {
+		case c == '\r':
+			if i+1 >= len(b) || b[i+1] != '\n' {
+				return false
+			}
+		case c == '\n':
+			if i == 0 || b[i-1] != '\r' {
+				return false
+			}
+			line = 0
+		case c == 0 || c >= 0x80:
+			return false
+		default:
+			if line++; line > maxLineLen {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mostlyASCII(b []byte) bool {
+	n := 0
+	for _, c := range b {
+		if c >= 0x80 || c < ' ' && c != '\r' && c != '\n' && c != '\t' {
+			n++
+		}
+	}
+	return n*4 <= len(b)
+}
+
+// writeEncoded copies r to w applying the Content-Transfer-Encoding cte.
+func writeEncoded(w io.Writer, cte string, r io.Reader, nl string) error {
+	var wc io.WriteCloser
+	switch strings.ToLower(cte) {
+	case "quoted-printable":
+		wc = quotedprintable.NewWriter(w)
+	case "base64":
+		wc = base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: w, nl: nl})
+	default:
+		_, err := io.Copy(w, r)
+		return err
+	}
+	if _, err := io.Copy(wc, r); err != nil {
+		return err
+	}
+	return wc.Close()
+}
+
+// lineWrapper breaks base64 output into lines of 76 characters.
+type lineWrapper struct {
+	w   io.Writer
+	nl  string
+	col int
+}
+
+const base64LineLen = 76
+
+func (lw *lineWrapper) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if lw.col == base64LineLen {
+			if _, err = io.WriteString(lw.w, lw.nl); err != nil {
+				return
+			}
+			lw.col = 0
+		}
+		k := base64LineLen - lw.col
+		if k > len(p) {
+			k = len(p)
+		}
+		var m int
+		m, err = lw.w.Write(p[:k])
+		n += m
+		lw.col += m
+		if err != nil {
+			return
+		}
+		p = p[k:]
+	}
+	return
+}
+
+// writeHeader writes h, sorted by key and folded to headerWidth, and the
+// blank line ending it. Its field names and values are checked, and
+// values encoded, as by a Writer with the HeaderEncodeWords policy.
+func writeHeader(w io.Writer, h textproto.MIMEHeader) error {
+	h, err := checkHeader(h, HeaderEncodeWords)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	writeHeaderLines(&b, h, nil, headerWidth, "\r\n")
+	_, err = b.WriteTo(w)
+	return err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// A Composer builds a typical email: a plain text and/or HTML body,
+// images referenced from the HTML and attachments. It produces
+// multipart/mixed, multipart/alternative and multipart/related
+// entities only where they are needed.
+type Composer struct {
+	// Header holds the message header fields, such as From, To,
+	// Subject and Date. Non-ASCII values are encoded whole as RFC 2047
+	// encoded-words, which suits unstructured fields like Subject;
+	// addresses with non-ASCII display names should be formatted by
+	// the caller, for example with net/mail's Address.String.
+	Header textproto.MIMEHeader
+
+	Text string
+	HTML string
+
+	// Inline holds entities referenced from HTML, usually created
+	// with NewInlineEntity.
+	Inline []*Entity
+
+	// Attachments are usually created with NewAttachment.
+	Attachments []*Entity
+}
+
+// NewComposer returns a Composer with an empty Header.
+func NewComposer() *Composer {
+	return &Composer{Header: make(textproto.MIMEHeader)}
+}
+
+// Entity returns the root entity of the message.
+func (c *Composer) Entity() *Entity {
+	var body *Entity
+	var html *Entity
+	if c.HTML != "" {
+		html = NewTextEntity("html", c.HTML)
+		if len(c.Inline) > 0 {
+			html = NewMultipartEntity("related", append([]*Entity{html}, c.Inline...)...)
+		}
+	}
+	switch {
+	case html != nil && c.Text != "":
+		body = NewMultipartEntity("alternative", NewTextEntity("plain", c.Text), html)
+	case html != nil:
+		body = html
+	default:
+		body = NewTextEntity("plain", c.Text)
+	}
+	if len(c.Attachments) > 0 {
+		body = NewMultipartEntity("mixed", append([]*Entity{body}, c.Attachments...)...)
+	}
+	for k, vv := range c.Header {
+		body.Header[k] = vv
+	}
+	return body
+}
+
+// WriteTo writes the complete message to w.
+func (c *Composer) WriteTo(w io.Writer) (int64, error) {
+	return c.Entity().WriteTo(w)
+}
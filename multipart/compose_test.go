@@ -0,0 +1,204 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cention-sany/mime"
+)
+
+func TestComposer(t *testing.T) {
+	c := NewComposer()
+	c.Header.Set("From", "a@example.com")
+	c.Header.Set("Subject", "héllo")
+	c.Text = "plain\ntext"
+	c.HTML = `<img src="cid:logo"> grüße`
+	c.Inline = []*Entity{NewInlineEntity("logo", "logo.png", "image/png",
+		bytes.NewReader([]byte{0x89, 'P', 'N', 'G', 0, 0xff}))}
+	c.Attachments = []*Entity{NewAttachment("résumé.txt", "text/plain",
+		strings.NewReader("cv"))}
+
+	var buf bytes.Buffer
+	n, err := c.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d; wrote %d", n, buf.Len())
+	}
+
+	m, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if g, e := m.Header.Get("Subject"), "=?utf-8?q?h=C3=A9llo?="; g != e {
+		t.Errorf("Subject = %q; want %q", g, e)
+	}
+	if g := m.Header.Get("Mime-Version"); g != "1.0" {
+		t.Errorf("MIME-Version = %q", g)
+	}
+	mixed, err := m.MultipartReader()
+	if err != nil {
+		t.Fatalf("mixed: %v", err)
+	}
+
+	p, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("alternative part: %v", err)
+	}
+	_, params, _ := parseMediaType(p.Header.Get("Content-Type"))
+	alt := NewReader(p, params["boundary"])
+	p, err = alt.NextPart()
+	if err != nil {
+		t.Fatalf("text part: %v", err)
+	}
+	if g := p.Header.Get("Content-Transfer-Encoding"); g != "7bit" {
+		t.Errorf("text part encoding = %q; want 7bit", g)
+	}
+	b, _ := ioutil.ReadAll(p)
+	if g, e := string(b), "plain\r\ntext"; g != e {
+		t.Errorf("text = %q; want %q", g, e)
+	}
+
+	p, err = alt.NextPart()
+	if err != nil {
+		t.Fatalf("related part: %v", err)
+	}
+	_, params, _ = parseMediaType(p.Header.Get("Content-Type"))
+	rel := NewReader(p, params["boundary"])
+	p, err = rel.NextPart()
+	if err != nil {
+		t.Fatalf("html part: %v", err)
+	}
+	// quoted-printable is decoded and its header hidden by Part
+	b, _ = ioutil.ReadAll(p)
+	if g, e := string(b), `<img src="cid:logo"> grüße`; g != e {
+		t.Errorf("html = %q; want %q", g, e)
+	}
+	p, err = rel.NextPart()
+	if err != nil {
+		t.Fatalf("inline part: %v", err)
+	}
+	if g := p.Header.Get("Content-Transfer-Encoding"); g != "base64" {
+		t.Errorf("inline encoding = %q; want base64", g)
+	}
+	if g := p.Header.Get("Content-Id"); g != "<logo>" {
+		t.Errorf("Content-ID = %q", g)
+	}
+
+	p, err = mixed.NextPart()
+	if err != nil {
+		t.Fatalf("attachment: %v", err)
+	}
+	if g, e := p.Header.Get("Content-Disposition"),
+		"attachment; filename*=utf-8''r%C3%A9sum%C3%A9.txt"; g != e {
+		t.Errorf("Content-Disposition = %q; want %q", g, e)
+	}
+	if g, e := p.FileName(), "résumé.txt"; g != e {
+		t.Errorf("FileName() = %q; want %q", g, e)
+	}
+}
+
+func TestComposerHeaderChecked(t *testing.T) {
+	c := NewComposer()
+	c.Header.Set("Content-Type", `text/plain; charset=utf-8; name="日本.txt"`)
+	c.Text = "text"
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	m, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, params, err := parseMediaType(m.Header.Get("Content-Type")); err != nil || params["name"] != "日本.txt" {
+		t.Errorf("Content-Type %q: %q, %v", m.Header.Get("Content-Type"), params, err)
+	}
+
+	for _, k := range []string{"X-A\r\nBcc", "X A", "X:A", ""} {
+		c := NewComposer()
+		c.Header[k] = []string{"v"}
+		c.Text = "text"
+		buf.Reset()
+		if _, err := c.WriteTo(&buf); err == nil {
+			t.Errorf("field name %q accepted: %q", k, buf.String())
+		} else if _, ok := err.(*HeaderError); !ok {
+			t.Errorf("field name %q: %v; want *HeaderError", k, err)
+		}
+	}
+}
+
+func TestChooseTransferEncoding(t *testing.T) {
+	tests := []struct {
+		typ, body, want string
+	}{
+		{"text/plain", "ascii\r\nonly", "7bit"},
+		{"text/plain", "bare\nnewline", "quoted-printable"},
+		{"text/plain", strings.Repeat("x", maxLineLen+1), "quoted-printable"},
+		{"text/plain", "\xff\xfe\xfd", "base64"},
+		{"image/png", "\x89PNG", "base64"},
+		{"message/rfc822", "Subject: é\r\n\r\n", "8bit"},
+	}
+	for _, tt := range tests {
+		if g, _ := chooseTransferEncoding(tt.typ, strings.NewReader(tt.body)); g != tt.want {
+			t.Errorf("chooseTransferEncoding(%q, %q) = %q; want %q", tt.typ, tt.body, g, tt.want)
+		}
+	}
+}
+
+func TestComposerLongHeaders(t *testing.T) {
+	subject := strings.Repeat("héllo wörld ", 60)
+	names := []string{strings.Repeat("ü", 200) + ".txt", strings.Repeat("a", 200) + ".txt"}
+	c := NewComposer()
+	c.Header.Set("Subject", subject)
+	c.Text = "text"
+	for _, name := range names {
+		c.Attachments = append(c.Attachments, NewAttachment(name, "", strings.NewReader("x")))
+	}
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		// a folded line holds at most one encoded-word, and a field
+		// line the field name and one too
+		if len(line) > maxLineLen || strings.HasPrefix(line, " ") && len(line) > 78 {
+			t.Errorf("line of %d bytes: %q", len(line), line)
+		}
+	}
+
+	m, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	dec := new(mime.WordDecoder)
+	if g, err := dec.DecodeHeader(m.Header.Get("Subject")); g != subject {
+		t.Errorf("Subject = %q, %v; want %q", g, err, subject)
+	}
+	mixed, err := m.MultipartReader()
+	if err != nil {
+		t.Fatalf("mixed: %v", err)
+	}
+	if _, err := mixed.NextPart(); err != nil {
+		t.Fatalf("text part: %v", err)
+	}
+	for _, name := range names {
+		p, err := mixed.NextPart()
+		if err != nil {
+			t.Fatalf("attachment: %v", err)
+		}
+		if g := p.FileName(); g != name {
+			t.Errorf("FileName() = %q; want %q", g, name)
+		}
+		_, params, _ := parseMediaType(p.Header.Get("Content-Type"))
+		if g, err := dec.DecodeHeader(params["name"]); g != name {
+			t.Errorf("name = %q, %v; want %q", g, err, name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrAbandoned is returned by NextPart after a part was closed before
+// its end by a Reader with SetDrainOnClose(false).
+var ErrAbandoned = errors.New("multipart: part abandoned before its end")
+
+// SetContext makes reading the input of r, by NextPart, Part.Read,
+// ReadForm and the Readers returned by Part.MultipartReader, fail with
+// ctx.Err() once ctx is done, even while a read of the input is
+// blocked. From then on NextPart returns that error. The blocked read
+// is left to finish in the background.
+func (r *Reader) SetContext(ctx context.Context) {
+	r.ctx = ctx
+	if cr, ok := r.src.r.(*ctxReader); ok {
+		cr.ctx = ctx
+		return
+	}
+	r.src.r = &ctxReader{r: r.src.r, ctx: ctx}
+}
+
+// SetDrainOnClose sets whether Part.Close reads the rest of its part,
+// as it does by default, so that NextPart can go on to the next part.
+// A caller abandoning the message would rather not read a large part it
+// does not want: with drain off, closing a part that was not read to
+// its end makes NextPart return ErrAbandoned.
+func (r *Reader) SetDrainOnClose(drain bool) {
+	r.noDrain = !drain
+}
+
+// atEnd reports whether the body of p was read to its end, looking for
+// the delimiter if all of the body found so far was read.
+func (p *Part) atEnd() bool {
+	if p.offsets.BodyEnd >= 0 {
+		return true
+	}
+	if p.safe == 0 && !p.last && p.scan() != nil {
+		return false
+	}
+	return p.safe == 0 && p.last
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// ctxReader reads r until ctx is done. Reads run in a goroutine into
+// buf, so that a blocked read can be given up.
+type ctxReader struct {
+	r    io.Reader
+	ctx  context.Context
+	buf  []byte
+	rest []byte // read into buf and not returned yet
+	res  chan readResult
+	err  error // of the last read, returned once rest is drained
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if len(cr.rest) > 0 {
+		n := copy(p, cr.rest)
+		cr.rest = cr.rest[n:]
+		if len(cr.rest) == 0 {
+			return n, cr.err
+		}
+		return n, nil
+	}
+	if cr.err != nil {
+		// never read again after an error
+		return 0, cr.err
+	}
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if cr.ctx.Done() == nil {
+		n, err := cr.r.Read(p)
+		cr.err = err
+		return n, err
+	}
+	if cr.res == nil {
+		cr.res = make(chan readResult, 1)
+		if cap(cr.buf) < len(p) {
+			cr.buf = make([]byte, len(p))
+		}
+		buf := cr.buf[:len(p)]
+		go func() {
+			n, err := cr.r.Read(buf)
+			cr.res <- readResult{n, err}
+		}()
+	}
+	select {
+	case rr := <-cr.res:
+		cr.res = nil
+		cr.err = rr.err
+		n := copy(p, cr.buf[:rr.n])
+		cr.rest = cr.buf[n:rr.n]
+		if len(cr.rest) > 0 {
+			return n, nil
+		}
+		return n, rr.err
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	}
+}
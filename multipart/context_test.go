@@ -0,0 +1,107 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderContext(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte("--b\r\nX-A: 1\r\n\r\nstart of a long body"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewReader(pr, "b")
+	r.SetContext(ctx)
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	time.AfterFunc(20*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := ioutil.ReadAll(p) // blocks on the pipe
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Read = %v; want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read not interrupted by cancel")
+	}
+	if _, err := r.NextPart(); err != context.Canceled {
+		t.Errorf("NextPart after cancel = %v; want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	r = NewReader(strings.NewReader(formBody), "b")
+	r.SetContext(ctx)
+	if _, err := r.ReadForm(1 << 10); err != context.Canceled {
+		t.Errorf("ReadForm with canceled context = %v", err)
+	}
+
+	// a live context reads through to the end of the input
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	r = NewReader(strings.NewReader(formBody), "b")
+	r.SetContext(ctx)
+	p, err = r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart with live context: %v", err)
+	}
+	if b, err := ioutil.ReadAll(p); err != nil || string(b) != "value" {
+		t.Errorf("ReadAll with live context = %q, %v", b, err)
+	}
+	f, err := r.ReadForm(1 << 10)
+	if err != nil {
+		t.Fatalf("ReadForm with live context: %v", err)
+	}
+	f.RemoveAll()
+	if len(f.File["big"]) != 1 || len(f.Value["stream"]) != 1 {
+		t.Errorf("ReadForm with live context: %v %v", f.Value, f.File)
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Errorf("NextPart at end with live context = %v; want io.EOF", err)
+	}
+
+	r = NewReader(strings.NewReader(formBody), "b")
+	r.SetContext(context.Background())
+	if _, err := r.ReadForm(1 << 10); err != nil {
+		t.Errorf("ReadForm with background context: %v", err)
+	}
+}
+
+func TestDrainOnClose(t *testing.T) {
+	body := "--b\r\n\r\nshort\r\n--b\r\n\r\n" + strings.Repeat("x", 1<<20) + "\r\n--b\r\n\r\nlast\r\n--b--\r\n"
+	src := &countReader{r: strings.NewReader(body)}
+	r := NewReader(src, "b")
+	r.SetDrainOnClose(false)
+
+	p, _ := r.NextPart()
+	if b, _ := ioutil.ReadAll(p); string(b) != "short" {
+		t.Fatalf("first part = %q", b)
+	}
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart after a part read to its end: %v", err)
+	}
+	p.Read(make([]byte, 10))
+	p.Close()
+	if _, err := r.NextPart(); err != ErrAbandoned {
+		t.Errorf("NextPart after abandoning = %v; want ErrAbandoned", err)
+	}
+	if src.n > 64<<10 {
+		t.Errorf("read %d bytes of the input; want the large part left unread", src.n)
+	}
+}
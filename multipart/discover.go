@@ -0,0 +1,132 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+// discoverWindow is how much of a body is examined to discover its
+// boundary.
+const discoverWindow = 64 << 10
+
+// ErrNoBoundary is returned by DiscoverReader when no plausible
+// boundary is found.
+var ErrNoBoundary = errors.New("multipart: no boundary found")
+
+// DiscoverReader creates a new multipart Reader reading from r, finding
+// the boundary from the body itself: the first "--xyz" delimiter line
+// whose token recurs and that is closed by "--xyz--". If the body is
+// longer than can be examined, a token delimiting at least two parts is
+// accepted without its final delimiter.
+//
+// hint is the boundary from the Content-Type header, if there was one.
+// A discovered boundary that matches it ignoring case, or that it is a
+// truncation of, is preferred.
+func DiscoverReader(r io.Reader, hint string) (*Reader, error) {
+	br := bufio.NewReaderSize(r, discoverWindow)
+	boundary, err := discoverBoundary(br, hint)
+	if err != nil {
+		return nil, err
+	}
+	return newReader(br, boundary, false), nil
+}
+
+// Boundary returns the boundary the Reader splits parts on.
+func (r *Reader) Boundary() string {
+	return r.boundary
+}
+
+// discoverBoundary finds the boundary in the data buffered by br
+// without consuming it.
+func discoverBoundary(br *bufio.Reader, hint string) (string, error) {
+	peek, err := br.Peek(discoverWindow)
+	complete := err != nil
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	if b := sniffBoundary(peek, hint, complete); b != "" {
+		return b, nil
+	}
+	return "", ErrNoBoundary
+}
+
+type boundaryCandidate struct {
+	token string
+	count int  // delimiter lines
+	final bool // seen the final delimiter
+}
+
+func sniffBoundary(body []byte, hint string, complete bool) string {
+	var cands []*boundaryCandidate
+	index := make(map[string]*boundaryCandidate)
+	for len(body) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(body, '\n'); i != -1 {
+			line, body = body[:i], body[i+1:]
+		} else {
+			line, body = body, nil
+		}
+		line = bytes.TrimRight(line, " \t\r")
+		if !bytes.HasPrefix(line, []byte("--")) {
+			continue
+		}
+		tok, final := string(line[2:]), false
+		if strings.HasSuffix(tok, "--") && isBoundary(tok[:len(tok)-2]) {
+			tok, final = tok[:len(tok)-2], true
+		}
+		if !isBoundary(tok) {
+			continue
+		}
+		c := index[tok]
+		if c == nil {
+			c = &boundaryCandidate{token: tok}
+			index[tok] = c
+			cands = append(cands, c)
+		}
+		if final {
+			c.final = true
+		} else {
+			c.count++
+		}
+	}
+	var found string
+	for _, c := range cands {
+		if c.count == 0 || !c.final && (complete || c.count < 2) {
+			continue
+		}
+		if hint == "" {
+			return c.token
+		}
+		if strings.EqualFold(c.token, hint) || strings.HasPrefix(c.token, hint) {
+			return c.token
+		}
+		if found == "" {
+			found = c.token
+		}
+	}
+	return found
+}
+
+// isBoundary reports whether s is a valid boundary as defined by RFC
+// 2046 section 5.1.1.
+func isBoundary(s string) bool {
+	if len(s) < 1 || len(s) > 70 || s[len(s)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("'()+_,-./:=? ", c) != -1 {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const discoverBody = `This is a message with multiple parts in MIME format.
+-----Original Message-----
+--outer
+Content-Type: multipart/alternative; boundary=inner
+
+--inner
+
+alt one
+--inner
+
+alt two
+--inner--
+--outer
+
+second
+--outer--
+`
+
+func TestSniffBoundary(t *testing.T) {
+	tests := []struct {
+		body, hint string
+		complete   bool
+		want       string
+	}{
+		{discoverBody, "", true, "outer"},
+		{discoverBody, "INNER", true, "inner"},
+		{discoverBody, "out", true, "outer"},
+		{discoverBody, "nomatch", true, "outer"},
+		{"--a\n\nx\n--a\n\ny\n", "", true, ""},   // no final delimiter
+		{"--a\n\nx\n--a\n\ny\n", "", false, "a"}, // ... beyond the window
+		{"--a\n\nx\n", "", false, ""},
+		{"no delimiters at all\n", "", true, ""},
+	}
+	for i, tt := range tests {
+		if g := sniffBoundary([]byte(tt.body), tt.hint, tt.complete); g != tt.want {
+			t.Errorf("%d. sniffBoundary(hint %q) = %q; want %q", i, tt.hint, g, tt.want)
+		}
+	}
+}
+
+func TestDiscoverReader(t *testing.T) {
+	r, err := DiscoverReader(strings.NewReader(strings.Replace(discoverBody, "\n", "\r\n", -1)), "")
+	if err != nil {
+		t.Fatalf("DiscoverReader: %v", err)
+	}
+	if r.Boundary() != "outer" {
+		t.Errorf("Boundary() = %q", r.Boundary())
+	}
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	// drop the boundary parameter, to be rediscovered in recovery mode
+	p.Header.Set("Content-Type", "multipart/alternative")
+	if _, err := p.MultipartReader(); err != ErrNotMultipart {
+		t.Errorf("strict MultipartReader() = %v; want ErrNotMultipart", err)
+	}
+	r.SetRecovery(true)
+	nr, err := p.MultipartReader()
+	if err != nil {
+		t.Fatalf("recovery MultipartReader(): %v", err)
+	}
+	for _, want := range []string{"alt one", "alt two"} {
+		np, err := nr.NextPart()
+		if err != nil {
+			t.Fatalf("inner NextPart: %v", err)
+		}
+		if b, _ := ioutil.ReadAll(np); string(b) != want {
+			t.Errorf("inner part = %q; want %q", b, want)
+		}
+	}
+
+	if _, err := DiscoverReader(strings.NewReader("plain text\r\n"), ""); err != ErrNoBoundary {
+		t.Errorf("DiscoverReader on plain text = %v; want ErrNoBoundary", err)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/cention-sany/net/textproto"
+)
+
+// ErrNoDeliveryStatus is returned when a message carries neither a
+// message/delivery-status part nor any recognisable bounce text.
+var ErrNoDeliveryStatus = errors.New("multipart: no delivery status found")
+
+// maxBounceText limits how much of a human-readable part is scanned by
+// the bounce heuristics.
+const maxBounceText = 64 << 10
+
+// DeliveryStatus is a parsed delivery status notification.
+type DeliveryStatus struct {
+	// Per-message fields of the message/delivery-status part.
+	ReportingMTA       string
+	ArrivalDate        string
+	OriginalEnvelopeID string
+	PerMessage         textproto.MIMEHeader
+
+	Recipients []RecipientStatus
+
+	// OriginalHeader is the header of the returned message, taken
+	// from the text/rfc822-headers or message/rfc822 part, if any.
+	OriginalHeader    textproto.MIMEHeader
+	OriginalMessageID string
+
+	// Heuristic is true when the status was guessed from the
+	// human-readable text of a non-standard bounce.
+	Heuristic bool
+}
+
+// RecipientStatus holds the per-recipient fields of a delivery status
+// notification. Address and diagnostic values have their type prefix
+// ("rfc822;", "smtp;") removed; the raw values remain in Fields.
+type RecipientStatus struct {
+	FinalRecipient    string
+	OriginalRecipient string
+	Action            string // failed, delayed, delivered, relayed or expanded
+	Status            string // e.g. 5.1.1
+	DiagnosticCode    string
+	RemoteMTA         string
+	LastAttemptDate   string
+	Fields            textproto.MIMEHeader
+}
+
+// Failed reports whether the delivery to the recipient has permanently
+// failed.
+func (rs *RecipientStatus) Failed() bool {
+	if rs.Action != "" {
+		return strings.EqualFold(rs.Action, "failed")
+	}
+	return strings.HasPrefix(rs.Status, "5")
+}
+
+// typedValue strips the "type;" prefix of address-type and
+// diagnostic-type fields.
+func typedValue(v string) string {
+	if i := strings.IndexByte(v, ';'); i != -1 && isToken(strings.TrimSpace(v[:i])) {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?=`, c) != -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseDeliveryStatus parses the body of a message/delivery-status part:
+// a group of per-message fields followed by one group per recipient.
+// Missing blank lines between groups and recipient fields appearing in
+// the per-message group are tolerated: a recipient group also starts
+// where an Original-Recipient or Final-Recipient field repeats one of
+// the group before.
+func ParseDeliveryStatus(r io.Reader) (*DeliveryStatus, error) {
+	fields, err := splitGroups(bufio.NewReaderSize(r, peekBufferSize))
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(fields)
+	tp := textproto.NewReader(br)
+	ds := new(DeliveryStatus)
+	first := true
+	for {
+		if err := skipBlankLines(br); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		h, err := tp.ReadMIMEHeader()
+		if err != nil && err != io.EOF && len(h) == 0 {
+			return nil, err
+		}
+		if first {
+			first = false
+			ds.PerMessage = h
+			ds.ReportingMTA = typedValue(h.Get("Reporting-Mta"))
+			ds.ArrivalDate = h.Get("Arrival-Date")
+			ds.OriginalEnvelopeID = h.Get("Original-Envelope-Id")
+		}
+		if h.Get("Final-Recipient") != "" || h.Get("Original-Recipient") != "" ||
+			h.Get("Action") != "" {
+			ds.Recipients = append(ds.Recipients, newRecipientStatus(h))
+		}
+		if err != nil {
+			break
+		}
+	}
+	if ds.PerMessage == nil {
+		return nil, ErrNoDeliveryStatus
+	}
+	return ds, nil
+}
+
+func newRecipientStatus(h textproto.MIMEHeader) RecipientStatus {
+	return RecipientStatus{
+		FinalRecipient:    typedValue(h.Get("Final-Recipient")),
+		OriginalRecipient: typedValue(h.Get("Original-Recipient")),
+		Action:            strings.ToLower(strings.TrimSpace(h.Get("Action"))),
+		Status:            strings.TrimSpace(h.Get("Status")),
+		DiagnosticCode:    typedValue(h.Get("Diagnostic-Code")),
+		RemoteMTA:         typedValue(h.Get("Remote-Mta")),
+		LastAttemptDate:   h.Get("Last-Attempt-Date"),
+		Fields:            h,
+	}
+}
+
+// splitGroups returns the lines of br with a blank line added before an
+// Original-Recipient or Final-Recipient field that repeats one of its
+// group.
+func splitGroups(br *bufio.Reader) (*bytes.Buffer, error) {
+	var b bytes.Buffer
+	var orig, final bool // seen in the group
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		name := ""
+		if i := bytes.IndexByte(line, ':'); i > 0 && line[0] != ' ' && line[0] != '\t' {
+			name = textproto.CanonicalMIMEHeaderKey(string(bytes.TrimSpace(line[:i])))
+		}
+		switch {
+		case len(bytes.TrimRight(line, "\r\n")) == 0:
+			orig, final = false, false
+		case name == "Original-Recipient":
+			if orig || final {
+				b.WriteString("\r\n")
+				final = false
+			}
+			orig = true
+		case name == "Final-Recipient":
+			if final {
+				b.WriteString("\r\n")
+				orig = false
+			}
+			final = true
+		}
+		b.Write(line)
+		if err == io.EOF {
+			return &b, nil
+		}
+	}
+}
+
+// skipBlankLines consumes empty lines from br. It returns io.EOF if
+// nothing else is left.
+func skipBlankLines(br *bufio.Reader) error {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return err
+		}
+		switch {
+		case b[0] == '\n':
+			br.Discard(1)
+		case b[0] == '\r':
+			if b, _ = br.Peek(2); len(b) < 2 || b[1] != '\n' {
+				return nil
+			}
+			br.Discard(2)
+		default:
+			return nil
+		}
+	}
+}
+
+// ReadDeliveryReport reads the remaining parts of r, which should be
+// the body of a multipart/report message, and returns the delivery
+// status it carries. If there is no message/delivery-status part, the
+// human-readable parts are scanned for bounce text instead.
+func (r *Reader) ReadDeliveryReport() (*DeliveryStatus, error) {
+	var ds *DeliveryStatus
+	var orig textproto.MIMEHeader
+	var text bytes.Buffer
+	if err := r.collectReport(&ds, &orig, &text); err != nil {
+		return nil, err
+	}
+	if ds == nil {
+		ds = parseBounceText(text.Bytes())
+		if ds == nil {
+			return nil, ErrNoDeliveryStatus
+		}
+	}
+	if orig != nil {
+		ds.OriginalHeader = orig
+		ds.OriginalMessageID = orig.Get("Message-Id")
+	}
+	return ds, nil
+}
+
+func (r *Reader) collectReport(ds **DeliveryStatus, orig *textproto.MIMEHeader, text *bytes.Buffer) error {
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		typ, params, err := parseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			continue
+		}
+		switch {
+		case *ds == nil && (typ == MessageDeliveryStatus || typ == "message/global-delivery-status"):
+			s, err := ParseDeliveryStatus(p)
+			if err != nil {
+				return err
+			}
+			*ds = s
+		case *orig == nil && (typ == "text/rfc822-headers" || typ == "text/global-headers"):
+			if m, err := ReadMessage(p); m != nil {
+				*orig = m.Header
+			} else if err != nil {
+				return err
+			}
+		case *orig == nil && (typ == MessageRFC822 || typ == MessageGlobal):
+			if m, err := p.Message(); m != nil {
+				*orig = m.Header
+			} else if err != nil {
+				return err
+			}
+		case strings.HasPrefix(typ, "multipart/") && params["boundary"] != "":
+			// some MTAs wrap the report in another multipart
+			mr, err := p.MultipartReader()
+			if err != nil {
+				return err
+			}
+			if err := mr.collectReport(ds, orig, text); err != nil {
+				return err
+			}
+		case typ == "" || typ == "text/plain":
+			if text.Len() < maxBounceText {
+				io.CopyN(text, p, int64(maxBounceText-text.Len()))
+			}
+		}
+	}
+}
+
+// ReadBounce reads a complete bounce message from r and returns its
+// delivery status, whether it is a standard multipart/report or a
+// plain text bounce.
+func ReadBounce(r io.Reader) (*DeliveryStatus, error) {
+	m, err := ReadMessage(r)
+	if m == nil {
+		return nil, err
+	}
+	if mr, err := m.MultipartReader(); err == nil {
+		return mr.ReadDeliveryReport()
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(m.Body, maxBounceText))
+	if err != nil {
+		return nil, err
+	}
+	ds := parseBounceText(b)
+	if ds == nil {
+		return nil, ErrNoDeliveryStatus
+	}
+	return ds, nil
+}
+
+var (
+	// <user@example.com>: or user@example.com: at the start of a line,
+	// as written by qmail, postfix and exim.
+	bounceRcptRe = regexp.MustCompile(`^\s*<?([^\s<>@"]+@[^\s<>:]+)>?:?\s*(.*)$`)
+	// enhanced status code (RFC 3463)
+	bounceStatusRe = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+	// basic SMTP reply code
+	bounceReplyRe = regexp.MustCompile(`\b([45])\d\d\b`)
+	// Message-ID of the original message, quoted in the bounce text
+	bounceMsgIDRe = regexp.MustCompile(`(?im)^\s*Message-ID:\s*(<[^>\s]+>)`)
+)
+
+// parseBounceText guesses recipients and their status from the text
+// of a non-standard bounce. It returns nil if nothing was found.
+func parseBounceText(b []byte) *DeliveryStatus {
+	var rcpts []RecipientStatus
+	var cur *RecipientStatus
+	finish := func() {
+		if cur == nil {
+			return
+		}
+		cur.DiagnosticCode = strings.TrimSpace(cur.DiagnosticCode)
+		if m := bounceStatusRe.FindString(cur.DiagnosticCode); m != "" {
+			cur.Status = m
+		} else if m := bounceReplyRe.FindStringSubmatch(cur.DiagnosticCode); m != nil {
+			cur.Status = m[1] + ".0.0"
+		}
+		switch {
+		case strings.HasPrefix(cur.Status, "5"):
+			cur.Action = "failed"
+		case strings.HasPrefix(cur.Status, "4"):
+			cur.Action = "delayed"
+		}
+		if cur.Status != "" {
+			rcpts = append(rcpts, *cur)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			finish()
+			continue
+		}
+		if m := bounceRcptRe.FindStringSubmatch(line); m != nil &&
+			(strings.Contains(line, ":") || cur == nil) {
+			finish()
+			cur = &RecipientStatus{FinalRecipient: m[1], DiagnosticCode: m[2]}
+			continue
+		}
+		if cur != nil {
+			cur.DiagnosticCode += " " + strings.TrimSpace(line)
+		}
+	}
+	finish()
+	if len(rcpts) == 0 {
+		return nil
+	}
+	ds := &DeliveryStatus{Recipients: rcpts, Heuristic: true}
+	if m := bounceMsgIDRe.FindSubmatch(b); m != nil {
+		ds.OriginalMessageID = string(m[1])
+	}
+	return ds
+}
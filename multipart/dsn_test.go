@@ -0,0 +1,148 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const standardBounce = `From: MAILER-DAEMON@example.com
+Content-Type: multipart/report; report-type=delivery-status;
+	boundary="RPT"
+
+--RPT
+Content-Type: text/plain
+
+Your message could not be delivered.
+--RPT
+Content-Type: message/delivery-status
+
+Reporting-MTA: dns; mx.example.com
+Arrival-Date: Mon, 2 Jan 2006 15:04:05 +0000
+
+Final-Recipient: rfc822; nobody@example.org
+Action: failed
+Status: 5.1.1
+Diagnostic-Code: smtp; 550 5.1.1 User unknown
+
+
+Final-Recipient: rfc822; later@example.org
+Action: delayed
+Status: 4.4.1
+--RPT
+Content-Type: text/rfc822-headers
+
+Message-ID: <orig@example.com>
+Subject: hello
+--RPT--
+`
+
+const qmailBounce = `From: MAILER-DAEMON@example.com
+Subject: failure notice
+
+Hi. This is the qmail-send program at example.com.
+I'm afraid I wasn't able to deliver your message to the following addresses.
+
+<nobody@example.org>:
+192.0.2.1 does not like recipient.
+Remote host said: 550 sorry, no mailbox here by that name
+Giving up on 192.0.2.1.
+
+--- Below this line is a copy of the message.
+
+Message-ID: <orig@example.com>
+`
+
+func TestReadBounceStandard(t *testing.T) {
+	ds, err := ReadBounce(strings.NewReader(strings.Replace(standardBounce, "\n", "\r\n", -1)))
+	if err != nil {
+		t.Fatalf("ReadBounce: %v", err)
+	}
+	if ds.Heuristic {
+		t.Error("standard DSN parsed heuristically")
+	}
+	if g, e := ds.ReportingMTA, "mx.example.com"; g != e {
+		t.Errorf("ReportingMTA = %q; want %q", g, e)
+	}
+	if len(ds.Recipients) != 2 {
+		t.Fatalf("got %d recipients; want 2", len(ds.Recipients))
+	}
+	rs := ds.Recipients[0]
+	if rs.FinalRecipient != "nobody@example.org" || rs.Action != "failed" ||
+		rs.Status != "5.1.1" || rs.DiagnosticCode != "550 5.1.1 User unknown" {
+		t.Errorf("first recipient = %+v", rs)
+	}
+	if !rs.Failed() || ds.Recipients[1].Failed() {
+		t.Error("Failed() mismatch")
+	}
+	if g, e := ds.OriginalMessageID, "<orig@example.com>"; g != e {
+		t.Errorf("OriginalMessageID = %q; want %q", g, e)
+	}
+}
+
+func TestReadBounceHeuristic(t *testing.T) {
+	ds, err := ReadBounce(strings.NewReader(qmailBounce))
+	if err != nil {
+		t.Fatalf("ReadBounce: %v", err)
+	}
+	if !ds.Heuristic {
+		t.Error("expected heuristic result")
+	}
+	if len(ds.Recipients) != 1 {
+		t.Fatalf("got %d recipients; want 1: %+v", len(ds.Recipients), ds.Recipients)
+	}
+	rs := ds.Recipients[0]
+	if rs.FinalRecipient != "nobody@example.org" || rs.Status != "5.0.0" || !rs.Failed() {
+		t.Errorf("recipient = %+v", rs)
+	}
+	if g, e := ds.OriginalMessageID, "<orig@example.com>"; g != e {
+		t.Errorf("OriginalMessageID = %q; want %q", g, e)
+	}
+}
+
+func TestReadBounceNone(t *testing.T) {
+	_, err := ReadBounce(strings.NewReader("Subject: hi\n\njust a mail\n"))
+	if err != ErrNoDeliveryStatus {
+		t.Errorf("ReadBounce = %v; want ErrNoDeliveryStatus", err)
+	}
+}
+
+func TestParseDeliveryStatusGroups(t *testing.T) {
+	body := "Reporting-MTA: dns; mx.example.com\r\n\r\n" +
+		"Final-Recipient: rfc822; a@x\r\nAction: failed\r\nStatus: 5.1.1\r\n" +
+		"Final-Recipient: rfc822; b@x\r\nAction: delayed\r\nStatus: 4.4.1\r\n" +
+		"Original-Recipient: rfc822; c@x\r\nFinal-Recipient: rfc822; c@x\r\nAction: delivered\r\n"
+	ds, err := ParseDeliveryStatus(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rs := range ds.Recipients {
+		got = append(got, rs.OriginalRecipient+" "+rs.FinalRecipient+" "+rs.Action)
+	}
+	want := []string{" a@x failed", " b@x delayed", "c@x c@x delivered"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recipients %q; want %q", got, want)
+	}
+}
+
+func TestReadDeliveryReportDepth(t *testing.T) {
+	body := "--a\r\nContent-Type: multipart/report; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: multipart/mixed; boundary=c\r\n\r\n" +
+		"--c\r\nContent-Type: message/delivery-status\r\n\r\n" +
+		"Reporting-MTA: dns; mx\r\n\r\nFinal-Recipient: rfc822; a@x\r\nAction: failed\r\n" +
+		"--c--\r\n--b--\r\n--a--\r\n"
+	if _, err := NewReader(strings.NewReader(body), "a").ReadDeliveryReport(); err != nil {
+		t.Errorf("nested report: %v", err)
+	}
+	r := NewReader(strings.NewReader(body), "a")
+	r.SetOptions(ReaderOptions{MaxDepth: 1})
+	_, err := r.ReadDeliveryReport()
+	if le, ok := err.(*LimitError); !ok || le.Limit != "MaxDepth" {
+		t.Errorf("nested report over MaxDepth: %v; want MaxDepth *LimitError", err)
+	}
+}
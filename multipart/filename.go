@@ -0,0 +1,158 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cention-sany/mime"
+)
+
+// FileNameMode selects what Part.FileName returns.
+type FileNameMode int
+
+const (
+	// FileNameRaw returns the filename parameter as sent, after
+	// RFC 2231 decoding of filename*. This is the default.
+	FileNameRaw FileNameMode = iota
+
+	// FileNameNormalized returns the file name as by
+	// NormalizeFileName.
+	FileNameNormalized
+)
+
+// maxFileName is the longest file name NormalizeFileName returns, in
+// bytes, as most file systems allow.
+const maxFileName = 255
+
+// maxExt is the longest extension NormalizeFileName keeps when it
+// shortens a name; a longer suffix after a dot is not an extension.
+const maxExt = 16
+
+// SetFileNameMode sets what FileName returns for the parts of r.
+// Readers returned by Part.MultipartReader inherit the mode.
+func (r *Reader) SetFileNameMode(mode FileNameMode) {
+	r.fileNameMode = mode
+}
+
+// NormalizeFileName decodes a file name sent in any of the conventions
+// found in practice and makes it safe to use as the name of a file:
+//
+//   - RFC 2047 encoded-words,
+//   - percent-encoding, when every escape is valid and the result is
+//     UTF-8,
+//   - HTML numeric character references such as "&#26085;",
+//   - ISO-8859-1, when the name is not valid UTF-8,
+//
+// then strips any client directory, such as "C:\Users\me\", replaces
+// control and reserved characters with "_", trims spaces and dots at
+// either end and limits the length to 255 bytes, shortening the name
+// before its extension. It returns "" if nothing usable is left.
+func NormalizeFileName(name string) string {
+	if strings.Contains(name, "=?") {
+		dec := new(mime.WordDecoder)
+		if s, err := dec.DecodeHeader(name); err == nil {
+			name = s
+		}
+	}
+	if s, ok := percentDecode(name); ok {
+		name = s
+	}
+	name = decodeCharRefs(name)
+	if !utf8.ValidString(name) {
+		var b bytes.Buffer
+		for i := 0; i < len(name); i++ {
+			b.WriteRune(rune(name[i]))
+		}
+		name = b.String()
+	}
+	if i := strings.LastIndexAny(name, `/\`); i != -1 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if len(name) <= maxFileName {
+		return name
+	}
+	// shorten the stem, keeping the extension
+	stem, ext := name, ""
+	if i := strings.LastIndexByte(name, '.'); i > 0 && len(name)-i <= maxExt {
+		stem, ext = name[:i], name[i:]
+	}
+	for len(stem) > 0 && len(stem)+len(ext) > maxFileName {
+		_, size := utf8.DecodeLastRuneInString(stem)
+		stem = strings.TrimRight(stem[:len(stem)-size], " .")
+	}
+	return stem + ext
+}
+
+// percentDecode decodes the %XX escapes of s if it has any, all are
+// valid and the result is valid UTF-8.
+func percentDecode(s string) (string, bool) {
+	if !strings.Contains(s, "%") {
+		return "", false
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b = append(b, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", false
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		b = append(b, byte(c))
+		i += 2
+	}
+	if !utf8.Valid(b) {
+		return "", false
+	}
+	return string(b), true
+}
+
+// decodeCharRefs decodes the HTML numeric character references in s,
+// which browsers send for characters the form's charset lacks.
+func decodeCharRefs(s string) string {
+	i := strings.Index(s, "&#")
+	if i == -1 {
+		return s
+	}
+	var b bytes.Buffer
+	for i != -1 {
+		b.WriteString(s[:i])
+		s = s[i:]
+		end := strings.IndexByte(s, ';')
+		if end == -1 {
+			break
+		}
+		num, base := s[2:end], 10
+		if strings.HasPrefix(num, "x") || strings.HasPrefix(num, "X") {
+			num, base = num[1:], 16
+		}
+		if r, err := strconv.ParseUint(num, base, 32); err == nil && utf8.ValidRune(rune(r)) && r != 0 {
+			b.WriteRune(rune(r))
+			s = s[end+1:]
+		} else {
+			b.WriteString("&#")
+			s = s[2:]
+		}
+		i = strings.Index(s, "&#")
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeFileName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain.txt", "plain.txt"},
+		{"résumé.txt", "résumé.txt"},
+		{"r%C3%A9sum%C3%A9.txt", "résumé.txt"},
+		{"100%.txt", "100%.txt"},
+		{"%FF.txt", "%FF.txt"},
+		{"&#26085;&#x672C;.doc", "日本.doc"},
+		{"a&#;b", "a&#;b"},
+		{"=?utf-8?q?caf=C3=A9.txt?=", "café.txt"},
+		{"caf\xe9.txt", "café.txt"},
+		{`C:\Users\me\Desktop\report.pdf`, "report.pdf"},
+		{"/etc/passwd", "passwd"},
+		{"../../x", "x"},
+		{"..", ""},
+		{" a<b>:c?.txt. ", "a_b__c_.txt"},
+		{"new\x00line\n.txt", "new_line_.txt"},
+		{strings.Repeat("é", 200), strings.Repeat("é", 127)},
+		{strings.Repeat("é", 200) + ".pdf", strings.Repeat("é", 125) + ".pdf"},
+		{strings.Repeat("a", 300) + ". .pdf", strings.Repeat("a", 251) + ".pdf"},
+		{"a." + strings.Repeat("b", 300), "a." + strings.Repeat("b", 253)},
+	}
+	for _, tt := range tests {
+		if g := NormalizeFileName(tt.in); g != tt.want {
+			t.Errorf("NormalizeFileName(%q) = %q; want %q", tt.in, g, tt.want)
+		}
+	}
+}
+
+func TestFileNameMode(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"f\"; filename=\"C:\\\\dir\\\\a%20b.txt\"\r\n\r\nx\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"g\"; filename=\"..\"\r\n\r\ny\r\n" +
+		"--b--\r\n"
+	r := NewReader(strings.NewReader(body), "b")
+	p, _ := r.NextPart()
+	if g := p.FileName(); g != `C:\dir\a%20b.txt` {
+		t.Errorf("raw FileName = %q", g)
+	}
+
+	r = NewReader(strings.NewReader(body), "b")
+	r.SetFileNameMode(FileNameNormalized)
+	f, err := r.ReadForm(1 << 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := f.File["f"][0].Filename; g != "a b.txt" {
+		t.Errorf("normalized FileName = %q", g)
+	}
+	if fhs := f.File["g"]; len(fhs) != 1 || fhs[0].Filename != "" {
+		t.Errorf("file with unusable name: %v, values %v", fhs, f.Value)
+	}
+}
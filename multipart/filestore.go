@@ -0,0 +1,221 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// defaultMaxMemory is the memory a TempFileStore keeps files in when
+// FormOptions has no Store.
+const defaultMaxMemory = 32 << 20
+
+// ErrNotStored is returned by FileHeader.Open when the FileStore did not
+// keep the file.
+var ErrNotStored = errors.New("multipart: file not stored")
+
+// ErrDiskQuota is returned by TempFileStore.Store when its MaxDisk
+// quota would be exceeded.
+var ErrDiskQuota = errors.New("multipart: temporary file quota exceeded")
+
+// A FileStore stores the file parts of a form as ReadFormOptions reads
+// them, for example in an object store or through a hashing pipeline.
+type FileStore interface {
+	// Store reads the content of the file part described by fh from
+	// r and returns the stored file. It may return a nil StoredFile
+	// for content it consumed without keeping; Open then fails with
+	// ErrNotStored.
+	Store(fh *FileHeader, r io.Reader) (StoredFile, error)
+}
+
+// A StoredFile is a file kept by a FileStore.
+type StoredFile interface {
+	Open() (File, error)
+
+	// Remove releases the file. It is called by Form.RemoveAll.
+	Remove() error
+}
+
+// A TempFileStore is the FileStore used by ReadForm. It keeps files in
+// memory until MaxMemory bytes are used, and larger files in temporary
+// files. A TempFileStore is meant for a single form, such as that of one
+// HTTP request: MaxMemory and MaxDisk limit the files of that form, and
+// memory is not given back when files are removed. Its fields must not
+// be changed once it is in use.
+type TempFileStore struct {
+	MaxMemory int64 // limits the bytes of files in memory
+
+	Dir     string      // for temporary files, os.TempDir() if empty
+	Prefix  string      // of temporary file names, "multipart-" if empty
+	Mode    os.FileMode // of temporary files, 0600 if zero
+	MaxDisk int64       // limits the bytes in temporary files if positive
+
+	mu    sync.Mutex
+	mem   int64    // bytes of files in memory
+	disk  int64    // bytes in temporary files
+	files []string // temporary files, for RemoveOnDone
+	done  bool     // the context of RemoveOnDone is done
+}
+
+// NewTempFileStore returns a TempFileStore keeping up to maxMemory bytes
+// of files in memory.
+func NewTempFileStore(maxMemory int64) *TempFileStore {
+	return &TempFileStore{MaxMemory: maxMemory}
+}
+
+// Store implements FileStore.
+func (s *TempFileStore) Store(fh *FileHeader, r io.Reader) (StoredFile, error) {
+	var b bytes.Buffer
+	s.mu.Lock()
+	left := s.MaxMemory - s.mem
+	s.mu.Unlock()
+	n, err := io.CopyN(&b, r, left+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if s.keep(n) {
+		return memFile(b.Bytes()), nil
+	}
+	// too big, write to disk and flush buffer
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "multipart-"
+	}
+	file, err := ioutil.TempFile(s.Dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if s.Mode != 0 {
+		if err := file.Chmod(s.Mode); err != nil {
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+	var src io.Reader = io.MultiReader(&b, r)
+	if s.MaxDisk > 0 {
+		s.mu.Lock()
+		left := s.MaxDisk - s.disk
+		s.mu.Unlock()
+		src = io.LimitReader(src, left+1)
+	}
+	n, err = io.Copy(file, src)
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return s.add(file.Name(), n)
+}
+
+// keep reports whether a file of n bytes fits in the memory left, and
+// if so counts it as used.
+func (s *TempFileStore) keep(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mem+n > s.MaxMemory {
+		return false
+	}
+	s.mem += n
+	return true
+}
+
+// add records the temporary file name of n bytes.
+func (s *TempFileStore) add(name string, n int64) (StoredFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.MaxDisk > 0 && s.disk+n > s.MaxDisk {
+		os.Remove(name)
+		return nil, ErrDiskQuota
+	}
+	if s.done {
+		os.Remove(name)
+		return nil, context.Canceled
+	}
+	s.disk += n
+	s.files = append(s.files, name)
+	return &tempFile{s: s, name: name, size: n}, nil
+}
+
+// remove removes the temporary file t of s.
+func (s *TempFileStore) remove(t *tempFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, name := range s.files {
+		if name == t.name {
+			s.files = append(s.files[:i], s.files[i+1:]...)
+			s.disk -= t.size
+			return os.Remove(t.name)
+		}
+	}
+	return nil // already removed
+}
+
+// RemoveOnDone removes the temporary files of s, including those stored
+// later, when ctx is done, such as when the HTTP request the form came
+// with ends. From then on Store fails with context.Canceled. It makes
+// forgetting Form.RemoveAll harmless. Calling stop ends the wait for ctx
+// without removing anything, which releases the goroutine waiting for a
+// ctx that may never be done.
+func (s *TempFileStore) RemoveOnDone(ctx context.Context) (stop func()) {
+	stopc := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopc:
+			return
+		}
+		select {
+		case <-stopc: // stopped before ctx was done
+			return
+		default:
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.done = true
+		for _, name := range s.files {
+			os.Remove(name)
+		}
+		s.files = nil
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(stopc) }) }
+}
+
+// memFile is a file kept in memory.
+type memFile []byte
+
+func (m memFile) Open() (File, error) {
+	r := io.NewSectionReader(bytes.NewReader(m), 0, int64(len(m)))
+	return sectionReadCloser{r}, nil
+}
+
+func (m memFile) Remove() error { return nil }
+
+// tempFile is a temporary file of a TempFileStore.
+type tempFile struct {
+	s    *TempFileStore
+	name string
+	size int64
+}
+
+func (t *tempFile) Open() (File, error) { return os.Open(t.name) }
+
+func (t *tempFile) Remove() error { return t.s.remove(t) }
+
+// helper types to turn a []byte into a File
+
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+func (rc sectionReadCloser) Close() error {
+	return nil
+}
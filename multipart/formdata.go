@@ -6,11 +6,10 @@ package multipart
 
 import (
 	"bytes"
-	"errors"
+	"hash"
 	"io"
 	"io/ioutil"
 	//"net/textproto"
-	"os"
 
 	"github.com/cention-sany/net/textproto"
 )
@@ -22,15 +21,78 @@ import (
 // a Content-Disposition of "form-data".
 // It stores up to maxMemory bytes of the file parts in memory
 // and the remainder on disk in temporary files.
-func (r *Reader) ReadForm(maxMemory int64) (f *Form, err error) {
-	form := &Form{make(map[string][]string), make(map[string][]*FileHeader)}
+func (r *Reader) ReadForm(maxMemory int64) (*Form, error) {
+	return r.ReadFormOptions(&FormOptions{Store: NewTempFileStore(maxMemory)})
+}
+
+// A FieldHandler consumes a form part as it is read. It need not read
+// the part to its end.
+type FieldHandler func(p *Part) error
+
+// FormOptions controls how ReadFormOptions reads a form.
+type FormOptions struct {
+	// Store stores the file parts without a handler. If nil, a
+	// TempFileStore keeping up to 32 MB in memory is used.
+	Store FileStore
+
+	// Handlers maps field names to the handlers of their parts.
+	// Parts given to a handler are not added to the Form.
+	Handlers map[string]FieldHandler
+
+	// Hashes names the digests to compute of each file as it is
+	// read, such as {"sha256": sha256.New}. They are available from
+	// FileHeader.Digests.
+	Hashes map[string]func() hash.Hash
+
+	// DecodeCharset makes values transcoded to UTF-8 from the charset
+	// of their part's Content-Type or, failing that, of the value of
+	// the "_charset_" field. CharsetReader, if non-nil, converts from
+	// charsets other than utf-8, us-ascii and iso-8859-1, which are
+	// handled by default, and its errors fail ReadFormOptions. Without
+	// it, values in other charsets are kept as they are. Charsets are
+	// always lower-case.
+	DecodeCharset bool
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+	// Limits of the form; exceeding one fails with a *LimitError
+	// naming the field. Zero means no limit, except for
+	// MaxValueBytes, which then is 10 MB.
+	MaxValueBytes int64 // of all values together
+	MaxValues     int   // number of values
+	MaxFiles      int   // number of files
+	MaxFileSize   int64 // of each file
+	MaxFieldParts int   // parts with the same field name
+}
+
+// ReadFormOptions parses an entire multipart message whose parts have a
+// Content-Disposition of "form-data", streaming each part to its
+// handler or, for file parts, to the FileStore as it is read. A
+// multipart/mixed part holds several files of its field, each one a
+// FileHeader. On error, the files stored so far are removed. A nil opts
+// is the same as a zero FormOptions.
+func (r *Reader) ReadFormOptions(opts *FormOptions) (f *Form, err error) {
+	if opts == nil {
+		opts = new(FormOptions)
+	}
+	store := opts.Store
+	if store == nil {
+		store = NewTempFileStore(defaultMaxMemory)
+	}
+	form := &Form{Value: make(map[string][]string), File: make(map[string][]*FileHeader)}
 	defer func() {
 		if err != nil {
 			form.RemoveAll()
 		}
 	}()
 
-	maxValueBytes := int64(10 << 20) // 10 MB is a lot of text.
+	valueLimit := opts.MaxValueBytes
+	if valueLimit == 0 {
+		valueLimit = 10 << 20 // 10 MB is a lot of text.
+	}
+	maxValueBytes := valueLimit
+	fieldParts := make(map[string]int)
+	values, files := 0, 0
+	var raw []rawValue
 	for {
 		p, err := r.NextPart()
 		if err == io.EOF {
@@ -44,56 +106,148 @@ func (r *Reader) ReadForm(maxMemory int64) (f *Form, err error) {
 		if name == "" {
 			continue
 		}
+		fieldParts[name]++
+		if max := opts.MaxFieldParts; max > 0 && fieldParts[name] > max {
+			return nil, &LimitError{Limit: "MaxFieldParts", Max: int64(max), Field: name}
+		}
+		if h := opts.Handlers[name]; h != nil {
+			if err := h(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		nr, err := mixedReader(p)
+		if err != nil {
+			return nil, err
+		}
+		if nr != nil {
+			// RFC 2388 section 5.5: several files in one field
+			for {
+				np, err := nr.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				if files++; opts.MaxFiles > 0 && files > opts.MaxFiles {
+					return nil, &LimitError{Limit: "MaxFiles", Max: int64(opts.MaxFiles), Field: name}
+				}
+				fh, err := storeFile(opts, store, name, np.FileName(), np)
+				if err != nil {
+					return nil, err
+				}
+				form.addFile(name, fh)
+			}
+			continue
+		}
 		filename := p.FileName()
 
-		var b bytes.Buffer
-
-		if filename == "" {
+		// a file whose name normalizes to "" is still a file
+		if p.dispositionParams["filename"] == "" {
 			// value, store as string in memory
-			n, err := io.CopyN(&b, p, maxValueBytes)
+			if values++; opts.MaxValues > 0 && values > opts.MaxValues {
+				return nil, &LimitError{Limit: "MaxValues", Max: int64(opts.MaxValues), Field: name}
+			}
+			var b bytes.Buffer
+			n, err := io.CopyN(&b, p, maxValueBytes+1)
 			if err != nil && err != io.EOF {
 				return nil, err
 			}
+			if n > maxValueBytes {
+				return nil, &LimitError{Limit: "MaxValueBytes", Max: valueLimit, Field: name}
+			}
 			maxValueBytes -= n
-			if maxValueBytes == 0 {
-				return nil, errors.New("multipart: message too large")
+			form.addValue(name, b.String())
+			if opts.DecodeCharset {
+				raw = append(raw, rawValue{name, len(form.Value[name]) - 1, partCharset(p)})
 			}
-			form.Value[name] = append(form.Value[name], b.String())
 			continue
 		}
 
-		// file, store in memory or on disk
-		fh := &FileHeader{
-			Filename: filename,
-			Header:   p.Header,
+		if files++; opts.MaxFiles > 0 && files > opts.MaxFiles {
+			return nil, &LimitError{Limit: "MaxFiles", Max: int64(opts.MaxFiles), Field: name}
 		}
-		n, err := io.CopyN(&b, p, maxMemory+1)
-		if err != nil && err != io.EOF {
+		fh, err := storeFile(opts, store, name, filename, p)
+		if err != nil {
 			return nil, err
 		}
-		if n > maxMemory {
-			// too big, write to disk and flush buffer
-			file, err := ioutil.TempFile("", "multipart-")
-			if err != nil {
-				return nil, err
-			}
-			defer file.Close()
-			_, err = io.Copy(file, io.MultiReader(&b, p))
-			if err != nil {
-				os.Remove(file.Name())
-				return nil, err
-			}
-			fh.tmpfile = file.Name()
-		} else {
-			fh.content = b.Bytes()
-			maxMemory -= n
-		}
-		form.File[name] = append(form.File[name], fh)
+		form.addFile(name, fh)
 	}
 
+	if err := form.decodeValues(raw, opts); err != nil {
+		return nil, err
+	}
 	return form, nil
 }
 
+// mixedReader returns a Reader over the parts of p if p is
+// multipart/mixed, or nil if p is not or has no usable boundary.
+func mixedReader(p *Part) (*Reader, error) {
+	typ, _, err := parseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || typ != "multipart/mixed" {
+		return nil, nil
+	}
+	nr, err := p.MultipartReader()
+	if err == ErrNotMultipart {
+		return nil, nil
+	}
+	return nr, err
+}
+
+// storeFile stores the file part p of the field name in store.
+func storeFile(opts *FormOptions, store FileStore, name, filename string, p *Part) (*FileHeader, error) {
+	fh := &FileHeader{
+		Filename: filename,
+		Header:   p.Header,
+	}
+	var body io.Reader = p
+	if max := opts.MaxFileSize; max > 0 {
+		body = &fileLimitReader{r: p, n: max, err: &LimitError{Limit: "MaxFileSize", Max: max, Field: name}}
+	}
+	cw := &countWriter{w: ioutil.Discard}
+	ws := []io.Writer{cw}
+	hashes := make(map[string]hash.Hash, len(opts.Hashes))
+	for k, newHash := range opts.Hashes {
+		h := newHash()
+		hashes[k] = h
+		ws = append(ws, h)
+	}
+	body = io.TeeReader(body, io.MultiWriter(ws...))
+	var err error
+	if fh.stored, err = store.Store(fh, body); err != nil {
+		return nil, err
+	}
+	fh.Size = cw.n
+	if len(hashes) > 0 {
+		fh.Digests = make(map[string][]byte, len(hashes))
+		for k, h := range hashes {
+			fh.Digests[k] = h.Sum(nil)
+		}
+	}
+	return fh, nil
+}
+
+// fileLimitReader reads at most n bytes from r, and fails with err if
+// there are more.
+type fileLimitReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *fileLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		n, err = int(l.n), l.err
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
 // Form is a parsed multipart form.
 // Its File parts are stored either in memory or on disk,
 // and are accessible via the *FileHeader's Open method.
@@ -102,6 +256,35 @@ func (r *Reader) ReadForm(maxMemory int64) (f *Form, err error) {
 type Form struct {
 	Value map[string][]string
 	File  map[string][]*FileHeader
+
+	// Entries lists the values and files in the order of their parts.
+	Entries []FormEntry
+}
+
+// FormEntryKind tells whether a FormEntry is a value or a file.
+type FormEntryKind int
+
+const (
+	FormValue FormEntryKind = iota
+	FormFile
+)
+
+// A FormEntry locates a value or file of a Form: Value[Name][Index] or
+// File[Name][Index].
+type FormEntry struct {
+	Name  string
+	Kind  FormEntryKind
+	Index int
+}
+
+func (f *Form) addValue(name, v string) {
+	f.Entries = append(f.Entries, FormEntry{name, FormValue, len(f.Value[name])})
+	f.Value[name] = append(f.Value[name], v)
+}
+
+func (f *Form) addFile(name string, fh *FileHeader) {
+	f.Entries = append(f.Entries, FormEntry{name, FormFile, len(f.File[name])})
+	f.File[name] = append(f.File[name], fh)
 }
 
 // RemoveAll removes any temporary files associated with a Form.
@@ -109,11 +292,11 @@ func (f *Form) RemoveAll() error {
 	var err error
 	for _, fhs := range f.File {
 		for _, fh := range fhs {
-			if fh.tmpfile != "" {
-				e := os.Remove(fh.tmpfile)
-				if e != nil && err == nil {
-					err = e
-				}
+			if fh.stored == nil {
+				continue
+			}
+			if e := fh.stored.Remove(); e != nil && err == nil {
+				err = e
 			}
 		}
 	}
@@ -125,17 +308,21 @@ type FileHeader struct {
 	Filename string
 	Header   textproto.MIMEHeader
 
-	content []byte
-	tmpfile string
+	// Size is the size of the file and Digests its digests named as
+	// in FormOptions.Hashes, both computed over the bytes read by the
+	// FileStore.
+	Size    int64
+	Digests map[string][]byte
+
+	stored StoredFile
 }
 
 // Open opens and returns the FileHeader's associated File.
 func (fh *FileHeader) Open() (File, error) {
-	if b := fh.content; b != nil {
-		r := io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b)))
-		return sectionReadCloser{r}, nil
+	if fh.stored == nil {
+		return nil, ErrNotStored
 	}
-	return os.Open(fh.tmpfile)
+	return fh.stored.Open()
 }
 
 // File is an interface to access the file part of a multipart message.
@@ -147,13 +334,3 @@ type File interface {
 	io.Seeker
 	io.Closer
 }
-
-// helper types to turn a []byte into a File
-
-type sectionReadCloser struct {
-	*io.SectionReader
-}
-
-func (rc sectionReadCloser) Close() error {
-	return nil
-}
@@ -0,0 +1,459 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const formBody = "--b\r\nContent-Disposition: form-data; name=\"text\"\r\n\r\nvalue\r\n" +
+	"--b\r\nContent-Disposition: form-data; name=\"small\"; filename=\"s.txt\"\r\n\r\nsmall\r\n" +
+	"--b\r\nContent-Disposition: form-data; name=\"big\"; filename=\"b.txt\"\r\n\r\n" +
+	"0123456789\r\n" +
+	"--b\r\nContent-Disposition: form-data; name=\"stream\"\r\n\r\nstreamed\r\n" +
+	"--b--\r\n"
+
+func readFile(t *testing.T, fh *FileHeader) string {
+	f, err := fh.Open()
+	if err != nil {
+		t.Fatalf("Open %s: %v", fh.Filename, err)
+	}
+	defer f.Close()
+	b, _ := ioutil.ReadAll(f)
+	return string(b)
+}
+
+func TestReadForm(t *testing.T) {
+	f, err := NewReader(strings.NewReader(formBody), "b").ReadForm(8)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	defer f.RemoveAll()
+	if g := f.Value["text"]; len(g) != 1 || g[0] != "value" {
+		t.Errorf("text = %q", g)
+	}
+	small, big := f.File["small"][0], f.File["big"][0]
+	if g := readFile(t, small); g != "small" {
+		t.Errorf("small = %q", g)
+	}
+	if g := readFile(t, big); g != "0123456789" {
+		t.Errorf("big = %q", g)
+	}
+	if _, ok := small.stored.(memFile); !ok {
+		t.Errorf("small file stored as %T; want memFile", small.stored)
+	}
+	tf, ok := big.stored.(*tempFile)
+	if !ok {
+		t.Fatalf("big file stored as %T; want *tempFile", big.stored)
+	}
+	if err := f.RemoveAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tf.name); !os.IsNotExist(err) {
+		t.Errorf("temp file left after RemoveAll: %v", err)
+	}
+}
+
+// hashStore consumes files without keeping them.
+type hashStore struct {
+	names []string
+	sizes []int64
+}
+
+func (s *hashStore) Store(fh *FileHeader, r io.Reader) (StoredFile, error) {
+	n, err := io.Copy(ioutil.Discard, r)
+	s.names = append(s.names, fh.Filename)
+	s.sizes = append(s.sizes, n)
+	return nil, err
+}
+
+func TestReadFormOptions(t *testing.T) {
+	store := new(hashStore)
+	var streamed bytes.Buffer
+	f, err := NewReader(strings.NewReader(formBody), "b").ReadFormOptions(&FormOptions{
+		Store: store,
+		Handlers: map[string]FieldHandler{
+			"stream": func(p *Part) error {
+				_, err := io.Copy(&streamed, p)
+				return err
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ReadFormOptions: %v", err)
+	}
+	if g := strings.Join(store.names, ","); g != "s.txt,b.txt" || store.sizes[1] != 10 {
+		t.Errorf("stored %q sizes %v", g, store.sizes)
+	}
+	if _, err := f.File["small"][0].Open(); err != ErrNotStored {
+		t.Errorf("Open = %v; want ErrNotStored", err)
+	}
+	if streamed.String() != "streamed" {
+		t.Errorf("handler read %q", streamed.String())
+	}
+	if _, ok := f.Value["stream"]; ok {
+		t.Error("handled field added to the form")
+	}
+}
+
+func TestReadFormOptionsNil(t *testing.T) {
+	f, err := NewReader(strings.NewReader(formBody), "b").ReadFormOptions(nil)
+	if err != nil {
+		t.Fatalf("ReadFormOptions(nil): %v", err)
+	}
+	defer f.RemoveAll()
+	if v := f.Value["text"]; len(v) != 1 || v[0] != "value" {
+		t.Errorf("text = %q", v)
+	}
+}
+
+func TestTempFileStoreOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multipart-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &TempFileStore{Dir: dir, Prefix: "upload-", Mode: 0640, MaxDisk: 15}
+	s.RemoveOnDone(ctx)
+	f, err := NewReader(strings.NewReader(formBody), "b").ReadFormOptions(&FormOptions{Store: s})
+	if err != nil {
+		t.Fatalf("ReadFormOptions: %v", err)
+	}
+	tf := f.File["big"][0].stored.(*tempFile)
+	if filepath.Dir(tf.name) != dir || !strings.HasPrefix(filepath.Base(tf.name), "upload-") {
+		t.Errorf("temp file %s", tf.name)
+	}
+	if fi, err := os.Stat(tf.name); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("temp file mode %v, %v", fi.Mode(), err)
+	}
+
+	// with no memory, both files went to disk and used the 15 bytes
+	_, err = NewReader(strings.NewReader(formBody), "b").ReadFormOptions(&FormOptions{Store: s})
+	if err != ErrDiskQuota {
+		t.Errorf("over quota: %v; want ErrDiskQuota", err)
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, "*")); len(names) != 2 {
+		t.Errorf("files after failed read: %q", names)
+	}
+
+	cancel()
+	for i := 0; i < 100; i++ {
+		if _, err = os.Stat(tf.name); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !os.IsNotExist(err) {
+		t.Errorf("temp file left after the context ended: %v", err)
+	}
+	if err := f.RemoveAll(); err != nil {
+		t.Errorf("RemoveAll after cleanup: %v", err)
+	}
+}
+
+func TestTempFileStoreMemory(t *testing.T) {
+	s := NewTempFileStore(40)
+	done := make(chan StoredFile)
+	for i := 0; i < 8; i++ {
+		go func() {
+			sf, err := s.Store(&FileHeader{}, strings.NewReader("0123456789"))
+			if err != nil {
+				t.Error(err)
+			}
+			done <- sf
+		}()
+	}
+	inMemory := 0
+	for i := 0; i < 8; i++ {
+		sf := <-done
+		if _, ok := sf.(memFile); ok {
+			inMemory++
+		}
+		if sf != nil {
+			defer sf.Remove()
+		}
+	}
+	if inMemory != 4 {
+		t.Errorf("%d files in memory; want 4", inMemory)
+	}
+	if s.MaxMemory != 40 {
+		t.Errorf("MaxMemory changed to %d", s.MaxMemory)
+	}
+}
+
+func TestTempFileStoreStop(t *testing.T) {
+	s := NewTempFileStore(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := s.RemoveOnDone(ctx)
+	stop()
+	stop()
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	sf, err := s.Store(&FileHeader{}, strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("Store after stop: %v", err)
+	}
+	defer sf.Remove()
+	if _, err := os.Stat(sf.(*tempFile).name); err != nil {
+		t.Errorf("temp file: %v", err)
+	}
+}
+
+func TestReadFormLimits(t *testing.T) {
+	tests := []struct {
+		opts         FormOptions
+		limit, field string // "" if within limits
+	}{
+		{FormOptions{MaxValueBytes: 13, MaxValues: 2, MaxFiles: 2, MaxFileSize: 10, MaxFieldParts: 1}, "", ""},
+		{FormOptions{MaxValueBytes: 12}, "MaxValueBytes", "stream"},
+		{FormOptions{MaxValues: 1}, "MaxValues", "stream"},
+		{FormOptions{MaxFiles: 1}, "MaxFiles", "big"},
+		{FormOptions{MaxFileSize: 9}, "MaxFileSize", "big"},
+	}
+	for _, tt := range tests {
+		_, err := NewReader(strings.NewReader(formBody), "b").ReadFormOptions(&tt.opts)
+		if tt.limit == "" {
+			if err != nil {
+				t.Errorf("%+v: %v", tt.opts, err)
+			}
+			continue
+		}
+		if le, ok := err.(*LimitError); !ok || le.Limit != tt.limit || le.Field != tt.field {
+			t.Errorf("%+v: error %v; want %s *LimitError for %q", tt.opts, err, tt.limit, tt.field)
+		}
+	}
+
+	body := strings.Replace(formBody, `name="big"`, `name="small"`, 1)
+	_, err := NewReader(strings.NewReader(body), "b").ReadFormOptions(&FormOptions{MaxFieldParts: 1})
+	if le, ok := err.(*LimitError); !ok || le.Limit != "MaxFieldParts" || le.Field != "small" {
+		t.Errorf("repeated field: %v", err)
+	}
+}
+
+func TestReadFormHashes(t *testing.T) {
+	f, err := NewReader(strings.NewReader(formBody), "b").ReadFormOptions(&FormOptions{
+		Hashes: map[string]func() hash.Hash{"sha256": sha256.New},
+	})
+	if err != nil {
+		t.Fatalf("ReadFormOptions: %v", err)
+	}
+	defer f.RemoveAll()
+	fh := f.File["big"][0]
+	sum := sha256.Sum256([]byte("0123456789"))
+	if fh.Size != 10 || !bytes.Equal(fh.Digests["sha256"], sum[:]) {
+		t.Errorf("size %d, sha256 %x; want 10, %x", fh.Size, fh.Digests["sha256"], sum)
+	}
+	if fh := f.File["small"][0]; fh.Size != 5 {
+		t.Errorf("small size = %d", fh.Size)
+	}
+}
+
+func TestReadFormCharset(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\ncaf\xe9\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"b\"\r\n" +
+		"Content-Type: text/plain; charset=X-Upper\r\n\r\nshout\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"c\"\r\n" +
+		"Content-Type: text/plain; charset=x-unknown\r\n\r\n\xff\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"_charset_\"\r\n\r\niso-8859-1\r\n" +
+		"--b--\r\n"
+	var charsets []string
+	opts := &FormOptions{
+		DecodeCharset: true,
+		CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+			charsets = append(charsets, charset)
+			if charset != "x-upper" {
+				return nil, errors.New("unsupported")
+			}
+			b, _ := ioutil.ReadAll(input)
+			return bytes.NewReader(bytes.ToUpper(b)), nil
+		},
+	}
+	_, err := NewReader(strings.NewReader(body), "b").ReadFormOptions(opts)
+	if err == nil || strings.Join(charsets, ",") != "x-upper,x-unknown" {
+		t.Errorf("error %v after charsets %q; want the reader's error", err, charsets)
+	}
+
+	body = strings.Replace(body, "x-unknown", "utf-8", 1)
+	f, err := NewReader(strings.NewReader(body), "b").ReadFormOptions(opts)
+	if err != nil {
+		t.Fatalf("ReadFormOptions: %v", err)
+	}
+	for name, want := range map[string]string{"a": "café", "b": "SHOUT", "c": "\xff"} {
+		if g := f.Value[name][0]; g != want {
+			t.Errorf("%s = %q; want %q", name, g, want)
+		}
+	}
+
+	f, _ = NewReader(strings.NewReader(body), "b").ReadForm(0)
+	if g := f.Value["a"][0]; g != "caf\xe9" {
+		t.Errorf("without DecodeCharset a = %q", g)
+	}
+}
+
+func TestReadFormMixed(t *testing.T) {
+	body := "--AaB03x\r\nContent-Disposition: form-data; name=\"submit-name\"\r\n\r\nLarry\r\n" +
+		"--AaB03x\r\nContent-Disposition: form-data; name=\"files\"\r\n" +
+		"Content-Type: multipart/mixed; boundary=BbC04y\r\n\r\n" +
+		"--BbC04y\r\nContent-Disposition: file; filename=\"file1.txt\"\r\n" +
+		"Content-Type: text/plain\r\n\r\n... contents of file1.txt ...\r\n" +
+		"--BbC04y\r\nContent-Disposition: file; filename=\"file2.gif\"\r\n" +
+		"Content-Type: image/gif\r\nContent-Transfer-Encoding: binary\r\n\r\n...contents of file2.gif...\r\n" +
+		"--BbC04y--\r\n" +
+		"\r\n--AaB03x--\r\n"
+	f, err := NewReader(strings.NewReader(body), "AaB03x").ReadForm(1 << 10)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	fhs := f.File["files"]
+	if len(fhs) != 2 {
+		t.Fatalf("got %d files; want 2", len(fhs))
+	}
+	if fhs[0].Filename != "file1.txt" || readFile(t, fhs[0]) != "... contents of file1.txt ..." {
+		t.Errorf("first file %q", fhs[0].Filename)
+	}
+	if fhs[1].Filename != "file2.gif" || fhs[1].Header.Get("Content-Type") != "image/gif" {
+		t.Errorf("second file %q %v", fhs[1].Filename, fhs[1].Header)
+	}
+	if g := f.Value["submit-name"]; len(g) != 1 || g[0] != "Larry" {
+		t.Errorf("submit-name = %q", g)
+	}
+
+	_, err = NewReader(strings.NewReader(body), "AaB03x").ReadFormOptions(&FormOptions{MaxFiles: 1})
+	if le, ok := err.(*LimitError); !ok || le.Limit != "MaxFiles" || le.Field != "files" {
+		t.Errorf("MaxFiles: %v", err)
+	}
+}
+
+func TestWriteForm(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n1\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"f\"; filename=\"x.bin\"\r\n" +
+		"Content-Type: application/x-thing\r\nX-Extra: kept\r\n\r\nbinary\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"m\"\r\n" +
+		"Content-Type: multipart/mixed; boundary=c\r\n\r\n" +
+		"--c\r\nContent-Disposition: file; filename=\"y.txt\"\r\n\r\ny\r\n--c--\r\n" +
+		"\r\n--b--\r\n"
+	f, err := NewReader(strings.NewReader(body), "b").ReadForm(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.RemoveAll()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteForm(f); err != nil {
+		t.Fatalf("WriteForm: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewReader(&buf, w.Boundary()).ReadForm(1 << 10)
+	if err != nil {
+		t.Fatalf("ReadForm of written form: %v", err)
+	}
+	if v := g.Value["a"]; len(v) != 1 || v[0] != "1" {
+		t.Errorf("a = %q", v)
+	}
+	fh := g.File["f"][0]
+	if fh.Filename != "x.bin" || fh.Header.Get("X-Extra") != "kept" ||
+		fh.Header.Get("Content-Type") != "application/x-thing" || readFile(t, fh) != "binary" {
+		t.Errorf("f: %q %v", fh.Filename, fh.Header)
+	}
+	fh = g.File["m"][0]
+	if fh.Filename != "y.txt" || readFile(t, fh) != "y" {
+		t.Errorf("m: %q %v", fh.Filename, fh.Header)
+	}
+}
+
+func TestFormEntries(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"z\"\r\n\r\n1\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"f\"; filename=\"f.txt\"\r\n\r\nf\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n2\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"z\"\r\n\r\n3\r\n" +
+		"--b--\r\n"
+	f, err := NewReader(strings.NewReader(body), "b").ReadForm(1 << 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FormEntry{{"z", FormValue, 0}, {"f", FormFile, 0}, {"a", FormValue, 0}, {"z", FormValue, 1}}
+	if !reflect.DeepEqual(f.Entries, want) {
+		t.Errorf("Entries = %v; want %v", f.Entries, want)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteForm(f)
+	w.Close()
+	r := NewReader(&buf, w.Boundary())
+	var names []string
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		names = append(names, p.FormName())
+	}
+	if g := strings.Join(names, ","); g != "z,f,a,z" {
+		t.Errorf("written order %s; want z,f,a,z", g)
+	}
+
+	// fields added after ReadForm are written after the parsed ones
+	f.Value["z"] = append(f.Value["z"], "4")
+	f.Value["added"] = []string{"5"}
+	buf.Reset()
+	w = NewWriter(&buf)
+	if err := w.WriteForm(f); err != nil {
+		t.Fatalf("WriteForm: %v", err)
+	}
+	w.Close()
+	g, err := NewReader(&buf, w.Boundary()).ReadForm(1 << 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, FormEntry{"added", FormValue, 0}, FormEntry{"z", FormValue, 2})
+	if !reflect.DeepEqual(g.Entries, want) {
+		t.Errorf("Entries after adding fields = %v; want %v", g.Entries, want)
+	}
+	if v := g.Value["z"]; len(v) != 3 || v[2] != "4" {
+		t.Errorf("z = %q", v)
+	}
+}
+
+func TestReadFormMixedDepth(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"files\"\r\n" +
+		"Content-Type: multipart/mixed; boundary=c\r\n\r\n" +
+		"--c\r\nContent-Disposition: file; filename=\"a.txt\"\r\n\r\na\r\n--c--\r\n" +
+		"\r\n--b\r\nContent-Disposition: form-data; name=\"broken\"\r\n" +
+		"Content-Type: multipart/mixed\r\n\r\nno boundary\r\n" +
+		"--b--\r\n"
+	f, err := NewReader(strings.NewReader(body), "b").ReadForm(1 << 10)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	if len(f.File["files"]) != 1 || len(f.Value["broken"]) != 1 {
+		t.Errorf("files %v, values %v", f.File, f.Value)
+	}
+
+	r := NewReader(strings.NewReader(body), "b")
+	r.SetOptions(ReaderOptions{MaxDepth: 1})
+	r.depth = 1 // as if r were nested already
+	_, err = r.ReadForm(1 << 10)
+	if le, ok := err.(*LimitError); !ok || le.Limit != "MaxDepth" {
+		t.Errorf("ReadForm beyond MaxDepth = %v; want MaxDepth *LimitError", err)
+	}
+}
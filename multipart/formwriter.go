@@ -0,0 +1,106 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cention-sany/net/textproto"
+)
+
+// entries returns the entries of f: its Entries, followed by the values
+// and files they do not cover, such as those added after ReadForm, by
+// field name, values first.
+func (f *Form) entries() []FormEntry {
+	covered := make(map[FormEntry]bool, len(f.Entries))
+	for _, e := range f.Entries {
+		covered[e] = true
+	}
+	names := make([]string, 0, len(f.Value)+len(f.File))
+	for name := range f.Value {
+		names = append(names, name)
+	}
+	for name := range f.File {
+		if _, ok := f.Value[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	es := append([]FormEntry(nil), f.Entries...)
+	add := func(e FormEntry) {
+		if !covered[e] {
+			es = append(es, e)
+		}
+	}
+	for _, name := range names {
+		for i := range f.Value[name] {
+			add(FormEntry{name, FormValue, i})
+		}
+		for i := range f.File[name] {
+			add(FormEntry{name, FormFile, i})
+		}
+	}
+	return es
+}
+
+// WriteForm writes the values and files of f as form-data parts, in
+// the order of f.Entries, followed by those f.Entries does not cover.
+// Files keep the header of their part, and their content is read with
+// FileHeader.Open. The caller must still Close w.
+func (w *Writer) WriteForm(f *Form) error {
+	for _, e := range f.entries() {
+		if e.Kind == FormValue {
+			if e.Index >= len(f.Value[e.Name]) {
+				continue
+			}
+			if err := w.WriteField(e.Name, f.Value[e.Name][e.Index]); err != nil {
+				return err
+			}
+			continue
+		}
+		if e.Index >= len(f.File[e.Name]) {
+			continue
+		}
+		fh := f.File[e.Name][e.Index]
+		p, err := w.CreatePart(formFileHeader(e.Name, fh))
+		if err != nil {
+			return err
+		}
+		file, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(p, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formFileHeader returns the header to write the file fh of the field
+// name with: its own if it is a form-data header for name, or else one
+// with a form-data Content-Disposition for name and the original file
+// name, as for files of a multipart/mixed field.
+func formFileHeader(name string, fh *FileHeader) textproto.MIMEHeader {
+	h := copyHeader(fh.Header)
+	disp, params, err := parseMediaType(h.Get("Content-Disposition"))
+	if err == nil && disp == "form-data" && params["name"] == name {
+		return h
+	}
+	filename := params["filename"]
+	if filename == "" {
+		filename = fh.Filename
+	}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(name), escapeQuotes(filename)))
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", "application/octet-stream")
+	}
+	return h
+}
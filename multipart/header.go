@@ -0,0 +1,203 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cention-sany/mime"
+	"github.com/cention-sany/net/textproto"
+)
+
+// HeaderPolicy selects how a Writer treats part header values that
+// contain 8-bit bytes. Values with CR, LF, NUL or other control
+// characters except tab are always rejected.
+type HeaderPolicy int
+
+const (
+	// HeaderAllowUTF8 writes valid UTF-8 values unchanged, as allowed
+	// by RFC 6532 and by RFC 7578 for form-data, and rejects other
+	// 8-bit values. This is the default.
+	HeaderAllowUTF8 HeaderPolicy = iota
+
+	// HeaderEncodeWords encodes values with 8-bit bytes as RFC 2047
+	// encoded-words. In Content-Type and Content-Disposition, which
+	// must not hold encoded-words, quoted parameter values with 8-bit
+	// bytes are written as RFC 2231 extended parameters instead.
+	HeaderEncodeWords
+
+	// HeaderASCIIOnly rejects values with 8-bit bytes.
+	HeaderASCIIOnly
+)
+
+// A HeaderError is returned by CreatePart and the functions built on it
+// when a header field of the part cannot be written safely.
+type HeaderError struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e *HeaderError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("multipart: invalid header field name %q: %s", e.Key, e.Reason)
+	}
+	return fmt.Sprintf("multipart: invalid value %q for header field %q: %s", e.Value, e.Key, e.Reason)
+}
+
+// SetHeaderPolicy sets how part header values with 8-bit bytes are
+// written. Writers nested in w that are created afterwards inherit the
+// policy.
+func (w *Writer) SetHeaderPolicy(policy HeaderPolicy) {
+	w.policy = policy
+}
+
+// isFieldName reports whether s is a valid field name, a run of ftext
+// as defined by RFC 5322 section 3.6.8.
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 33 || c > 126 || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHeader validates header against policy and returns it, or a copy
+// with values encoded as required.
+func checkHeader(header textproto.MIMEHeader, policy HeaderPolicy) (textproto.MIMEHeader, error) {
+	out := header
+	copied := false
+	for k, vv := range header {
+		if !isFieldName(k) {
+			return nil, &HeaderError{Key: k, Reason: "not printable ASCII without colon"}
+		}
+		for i, v := range vv {
+			nv, err := checkValue(k, v, policy)
+			if err != nil {
+				return nil, err
+			}
+			if nv == v {
+				continue
+			}
+			if !copied {
+				out = copyHeader(header)
+				copied = true
+			}
+			out[k][i] = nv
+		}
+	}
+	return out, nil
+}
+
+func checkValue(k, v string, policy HeaderPolicy) (string, error) {
+	eight := false
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '\r' || c == '\n':
+			return "", &HeaderError{Key: k, Value: v, Reason: "contains a line break"}
+		case c == 0:
+			return "", &HeaderError{Key: k, Value: v, Reason: "contains NUL"}
+		case c < ' ' && c != '\t' || c == 0x7f:
+			return "", &HeaderError{Key: k, Value: v, Reason: "contains a control character"}
+		case c >= 0x80:
+			eight = true
+		}
+	}
+	if !eight {
+		return v, nil
+	}
+	switch policy {
+	case HeaderAllowUTF8:
+		if utf8.ValidString(v) {
+			return v, nil
+		}
+		return "", &HeaderError{Key: k, Value: v, Reason: "not valid UTF-8"}
+	case HeaderEncodeWords:
+		if !utf8.ValidString(v) {
+			return "", &HeaderError{Key: k, Value: v, Reason: "not valid UTF-8"}
+		}
+		if k != "Content-Type" && k != "Content-Disposition" {
+			return mime.QEncoding.Encode("utf-8", v), nil
+		}
+		if nv, ok := encodeParams(v); ok {
+			return nv, nil
+		}
+		return "", &HeaderError{Key: k, Value: v, Reason: "8-bit bytes outside a quoted parameter"}
+	}
+	return "", &HeaderError{Key: k, Value: v, Reason: "contains 8-bit bytes"}
+}
+
+var paramNameRe = regexp.MustCompile("^\\s*([!#$%&'+\\-.^_`|~0-9A-Za-z]+)\\s*=\\s*$")
+
+// encodeParams rewrites the quoted parameter values with 8-bit bytes of
+// the Content-Type or Content-Disposition value v as RFC 2231 extended
+// parameters of charset utf-8. It reports false if v has 8-bit bytes
+// elsewhere.
+func encodeParams(v string) (string, bool) {
+	var b bytes.Buffer
+	done := 0 // v[:done] is in b
+	for i := 0; i < len(v); i++ {
+		if v[i] >= 0x80 {
+			return "", false
+		}
+		if v[i] != '"' {
+			continue
+		}
+		var value bytes.Buffer // unescaped
+		eight := false
+		j := i + 1
+		for ; j < len(v) && v[j] != '"'; j++ {
+			if v[j] == '\\' && j+1 < len(v) {
+				j++
+			}
+			eight = eight || v[j] >= 0x80
+			value.WriteByte(v[j])
+		}
+		if !eight {
+			i = j
+			continue
+		}
+		if j == len(v) {
+			return "", false // unterminated
+		}
+		semi := strings.LastIndexByte(v[done:i], ';')
+		if semi < 0 {
+			return "", false
+		}
+		m := paramNameRe.FindStringSubmatch(v[done+semi+1 : i])
+		if m == nil {
+			return "", false
+		}
+		b.WriteString(v[done : done+semi+1])
+		b.WriteString(" " + m[1] + "*=utf-8''")
+		writePercent(&b, value.String())
+		done = j + 1
+		i = j
+	}
+	b.WriteString(v[done:])
+	return b.String(), true
+}
+
+// writePercent writes s to b with the bytes that are not attribute-char,
+// as defined by RFC 2231 section 7, percent-encoded.
+func writePercent(b *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", c) != -1 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(b, "%%%02X", c)
+	}
+}
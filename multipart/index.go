@@ -0,0 +1,89 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io"
+	"strings"
+
+	"github.com/cention-sany/net/textproto"
+)
+
+// An Index locates the parts of a multipart body held in an io.ReaderAt.
+// It is built in a single sequential pass; afterwards the parts can be
+// read in any order, and concurrently if the io.ReaderAt allows it, as
+// os.File does.
+type Index struct {
+	Parts []*IndexedPart
+}
+
+// An IndexedPart is a part found by NewIndex.
+type IndexedPart struct {
+	// Header is the header of the part as read. Unlike Part.Header,
+	// a quoted-printable Content-Transfer-Encoding is kept, as the
+	// body is not decoded.
+	Header textproto.MIMEHeader
+
+	// Offsets locates the part in the io.ReaderAt given to NewIndex.
+	Offsets PartOffsets
+
+	r io.ReaderAt
+}
+
+// NewIndex reads the multipart body of size bytes in r with the given
+// boundary and returns the index of its parts. An error from reading a
+// part is returned with the index of the parts before it.
+func NewIndex(r io.ReaderAt, size int64, boundary string) (*Index, error) {
+	return newIndex(r, 0, size, boundary)
+}
+
+// newIndex indexes the body of size bytes at off in r.
+func newIndex(r io.ReaderAt, off, size int64, boundary string) (*Index, error) {
+	mr := newReader(io.NewSectionReader(r, off, size), boundary, false)
+	mr.base = off
+	ix := new(Index)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return ix, nil
+		}
+		if err != nil {
+			return ix, err
+		}
+		h := p.Header
+		if _, ok := p.r.(partReader); !ok {
+			h = copyHeader(h)
+			h.Set("Content-Transfer-Encoding", "quoted-printable")
+		}
+		if err := p.Close(); err != nil {
+			return ix, err
+		}
+		ix.Parts = append(ix.Parts, &IndexedPart{Header: h, Offsets: p.offsets, r: r})
+	}
+}
+
+// Body returns a reader of the raw body of p, without
+// Content-Transfer-Encoding decoding.
+func (p *IndexedPart) Body() *io.SectionReader {
+	o := p.Offsets
+	return io.NewSectionReader(p.r, o.BodyStart, o.BodyEnd-o.BodyStart)
+}
+
+// Raw returns a reader of the header block and body of p as they
+// appear in the input.
+func (p *IndexedPart) Raw() *io.SectionReader {
+	o := p.Offsets
+	return io.NewSectionReader(p.r, o.HeaderStart, o.BodyEnd-o.HeaderStart)
+}
+
+// Index returns the index of the parts of p if p is itself multipart.
+func (p *IndexedPart) Index() (*Index, error) {
+	typ, params, err := parseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(typ, "multipart/") || params["boundary"] == "" {
+		return nil, ErrNotMultipart
+	}
+	o := p.Offsets
+	return newIndex(p.r, o.BodyStart, o.BodyEnd-o.BodyStart, params["boundary"])
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	const body = "--outer\r\nContent-Type: text/plain\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\ncaf=C3=A9\r\n" +
+		"--outer\r\nContent-Type: multipart/mixed; boundary=inner\r\n\r\n" +
+		"--inner\r\nX-N: 1\r\n\r\none\r\n--inner\r\nX-N: 2\r\n\r\ntwo\r\n--inner--\r\n" +
+		"\r\n--outer\r\nX-N: 3\r\n\r\nthree\r\n--outer--\r\n"
+	ix, err := NewIndex(strings.NewReader(body), int64(len(body)), "outer")
+	if err != nil {
+		t.Fatalf("NewIndex: %v", err)
+	}
+	if len(ix.Parts) != 3 {
+		t.Fatalf("got %d parts; want 3", len(ix.Parts))
+	}
+	if g := ix.Parts[0].Header.Get("Content-Transfer-Encoding"); g != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q", g)
+	}
+	if b, _ := ioutil.ReadAll(ix.Parts[0].Body()); string(b) != "caf=C3=A9" {
+		t.Errorf("raw body = %q", b)
+	}
+	if b, _ := ioutil.ReadAll(ix.Parts[2].Raw()); string(b) != "X-N: 3\r\n\r\nthree" {
+		t.Errorf("raw part = %q", b)
+	}
+
+	nested, err := ix.Parts[1].Index()
+	if err != nil {
+		t.Fatalf("nested Index: %v", err)
+	}
+	if _, err := ix.Parts[0].Index(); err != ErrNotMultipart {
+		t.Errorf("Index of text part = %v; want ErrNotMultipart", err)
+	}
+	parts := []*IndexedPart{ix.Parts[2], nested.Parts[1], nested.Parts[0]}
+	want := []string{"three", "two", "one"}
+	got := make([]string, len(parts))
+	var wg sync.WaitGroup
+	for i, p := range parts {
+		wg.Add(1)
+		go func(i int, p *IndexedPart) {
+			defer wg.Done()
+			b, _ := ioutil.ReadAll(p.Body())
+			got[i] = string(b)
+		}(i, p)
+	}
+	wg.Wait()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("part %d = %q; want %q", i, got[i], want[i])
+		}
+	}
+	if g := nested.Parts[1].Header.Get("X-N"); g != "2" {
+		t.Errorf("nested header X-N = %q", g)
+	}
+}
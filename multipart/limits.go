@@ -0,0 +1,114 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// ReaderOptions limits the resources a Reader uses. A zero field means
+// no limit. When a limit is exceeded, the method reading the input
+// returns a *LimitError, and from then on NextPart returns it too, also
+// on the Reader a nested Reader was created from.
+type ReaderOptions struct {
+	// MaxParts limits the number of parts, counting those of
+	// nested Readers returned by Part.MultipartReader.
+	MaxParts int
+
+	// MaxDepth limits how deep Readers returned by
+	// Part.MultipartReader can be nested.
+	MaxDepth int
+
+	// MaxHeaderBytes and MaxHeaderLines limit the size of the header
+	// block of a part, including line endings and continuation lines.
+	MaxHeaderBytes int
+	MaxHeaderLines int
+
+	// MaxPartSize limits the size of the body of a part, before any
+	// Content-Transfer-Encoding decoding.
+	MaxPartSize int64
+
+	// MaxTotalSize limits the number of bytes read from the input.
+	MaxTotalSize int64
+}
+
+// A LimitError reports that a limit of ReaderOptions or FormOptions was
+// exceeded. An HTTP server would typically answer it with 413 Request
+// Entity Too Large.
+type LimitError struct {
+	Limit string // name of the ReaderOptions or FormOptions field
+	Max   int64
+	Field string // form field name, for the limits of FormOptions
+}
+
+func (e *LimitError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("multipart: %s limit of %d exceeded by form field %q", e.Limit, e.Max, e.Field)
+	}
+	return fmt.Sprintf("multipart: %s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// SetOptions sets the limits of r. It should be called before the
+// first call to NextPart. Readers returned by Part.MultipartReader
+// inherit them.
+func (r *Reader) SetOptions(opts ReaderOptions) {
+	r.opts = opts
+	r.src.max = opts.MaxTotalSize
+}
+
+// limitState is shared by a Reader and its nested Readers.
+type limitState struct {
+	parts int   // read so far
+	err   error // that stopped the Readers, see stop
+}
+
+// isStopErr reports whether err stops the Reader for good: a limit was
+// exceeded, the context of the Reader is done or a part was abandoned.
+func isStopErr(err error) bool {
+	if _, ok := err.(*LimitError); ok {
+		return true
+	}
+	return err == context.Canceled || err == context.DeadlineExceeded || err == ErrAbandoned
+}
+
+// stop records err as the error NextPart of r and of the Readers
+// sharing its limits returns from now on if it is a stop error.
+func (r *Reader) stop(err error) error {
+	if isStopErr(err) && r.limits.err == nil {
+		r.limits.err = err
+	}
+	return err
+}
+
+// readHeaderBlock reads the header block of a part, up to and including
+// the blank line ending it, enforcing MaxHeaderBytes and MaxHeaderLines.
+func (r *Reader) readHeaderBlock() ([]byte, error) {
+	var b bytes.Buffer
+	lines := 0
+	for {
+		line, err := r.bufReader.ReadSlice('\n')
+		start := b.Len() == 0 || b.Bytes()[b.Len()-1] == '\n'
+		b.Write(line)
+		if max := r.opts.MaxHeaderBytes; max > 0 && b.Len() > max {
+			return nil, &LimitError{Limit: "MaxHeaderBytes", Max: int64(max)}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return b.Bytes(), err
+		}
+		if start && isNewline(line) {
+			return b.Bytes(), nil
+		}
+		lines++
+		if max := r.opts.MaxHeaderLines; max > 0 && lines > max {
+			return nil, &LimitError{Limit: "MaxHeaderLines", Max: int64(max)}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const limitsBody = "--b\r\nX-A: 1\r\nX-B: 2\r\n\r\n0123456789\r\n" +
+	"--b\r\nContent-Type: multipart/mixed; boundary=c\r\n\r\n" +
+	"--c\r\n\r\ninner\r\n--c--\r\n" +
+	"\r\n--b--\r\n"
+
+// readAllParts reads every part of r and of its nested Readers.
+func readAllParts(r *Reader) error {
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if nr, err := p.MultipartReader(); err == nil {
+			if err := readAllParts(nr); err != nil {
+				return err
+			}
+			continue
+		} else if _, ok := err.(*LimitError); ok {
+			return err
+		}
+		if _, err := ioutil.ReadAll(p); err != nil {
+			return err
+		}
+	}
+}
+
+func TestReaderOptions(t *testing.T) {
+	tests := []struct {
+		opts  ReaderOptions
+		limit string // "" if within limits
+	}{
+		{ReaderOptions{}, ""},
+		{ReaderOptions{MaxParts: 3}, ""},
+		{ReaderOptions{MaxParts: 2}, "MaxParts"},
+		{ReaderOptions{MaxDepth: 1}, ""},
+		{ReaderOptions{MaxHeaderLines: 2}, ""},
+		{ReaderOptions{MaxHeaderLines: 1}, "MaxHeaderLines"},
+		{ReaderOptions{MaxHeaderBytes: 45}, ""},
+		{ReaderOptions{MaxHeaderBytes: 44}, "MaxHeaderBytes"},
+		{ReaderOptions{MaxPartSize: 59}, ""},
+		{ReaderOptions{MaxPartSize: 9}, "MaxPartSize"},
+		{ReaderOptions{MaxTotalSize: int64(len(limitsBody))}, ""},
+		{ReaderOptions{MaxTotalSize: int64(len(limitsBody)) - 1}, "MaxTotalSize"},
+	}
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(limitsBody), "b")
+		r.SetOptions(tt.opts)
+		err := readAllParts(r)
+		if tt.limit == "" {
+			if err != nil {
+				t.Errorf("%+v: %v", tt.opts, err)
+			}
+			continue
+		}
+		le, ok := err.(*LimitError)
+		if !ok || le.Limit != tt.limit {
+			t.Errorf("%+v: error %v; want %s *LimitError", tt.opts, err, tt.limit)
+			continue
+		}
+		if _, err := r.NextPart(); err != le {
+			t.Errorf("%+v: NextPart after limit = %v; want %v", tt.opts, err, le)
+		}
+	}
+
+	// as if r were nested already
+	r := NewReader(strings.NewReader(limitsBody), "b")
+	r.SetOptions(ReaderOptions{MaxDepth: 1})
+	r.depth = 1
+	r.NextPart()
+	p, _ := r.NextPart()
+	if _, err := p.MultipartReader(); err == nil {
+		t.Error("MultipartReader beyond MaxDepth succeeded")
+	}
+}
+
+func TestReaderOptionsMessage(t *testing.T) {
+	inner := "Subject: nested\r\nContent-Type: multipart/mixed; boundary=c\r\n\r\n" +
+		"--c\r\n\r\none\r\n--c\r\n\r\ntwo\r\n--c\r\n\r\nthree\r\n--c--\r\n"
+	body := "--b\r\nContent-Type: message/rfc822\r\n\r\n" + inner + "\r\n--b--\r\n"
+
+	read := func(opts ReaderOptions) (*Reader, error) {
+		r := NewReader(strings.NewReader(body), "b")
+		r.SetOptions(opts)
+		p, err := r.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		m, err := p.Message()
+		if err != nil {
+			return nil, err
+		}
+		mr, err := m.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+		return mr, readAllParts(mr)
+	}
+	if _, err := read(ReaderOptions{MaxParts: 4}); err != nil {
+		t.Errorf("within MaxParts: %v", err)
+	}
+	if _, err := read(ReaderOptions{MaxParts: 3}); err == nil {
+		t.Error("nested message parts not counted for MaxParts")
+	}
+	if _, err := read(ReaderOptions{MaxDepth: 1}); err != nil {
+		t.Errorf("within MaxDepth: %v", err)
+	}
+	r := NewReader(strings.NewReader(body), "b")
+	r.SetOptions(ReaderOptions{MaxDepth: 1})
+	r.depth = 1 // as if r were nested already
+	p, _ := r.NextPart()
+	m, _ := p.Message()
+	if _, err := m.MultipartReader(); err == nil {
+		t.Error("message MultipartReader beyond MaxDepth succeeded")
+	}
+
+	// the nested parts are located in the outer input
+	r = NewReader(strings.NewReader(body), "b")
+	p, _ = r.NextPart()
+	m, _ = p.Message()
+	mr, _ := m.MultipartReader()
+	ip, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(ip)
+	if o := ip.Offsets(); body[o.BodyStart:o.BodyEnd] != "one" {
+		t.Errorf("nested part offsets %+v", o)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cention-sany/net/textproto"
+)
+
+// MessageDispositionNotification is the media type of the machine
+// readable part of a disposition notification.
+const MessageDispositionNotification = "message/disposition-notification"
+
+// ErrNoDispositionNotification is returned when a multipart/report has
+// no message/disposition-notification part.
+var ErrNoDispositionNotification = errors.New("multipart: no disposition notification found")
+
+// DispositionNotification is a message disposition notification (read
+// receipt) as defined by RFC 8098.
+type DispositionNotification struct {
+	// Text is the human-readable explanation sent as the first part.
+	Text string
+
+	ReportingUA       string
+	MDNGateway        string
+	OriginalRecipient string
+	FinalRecipient    string
+	OriginalMessageID string
+
+	// The Disposition field,
+	// "ActionMode/SendingMode; DispositionType/Modifiers".
+	ActionMode      string // manual-action or automatic-action
+	SendingMode     string // MDN-sent-manually or MDN-sent-automatically
+	DispositionType string // displayed, deleted, dispatched or processed
+	Modifiers       []string
+
+	Error string
+
+	// Fields holds every field of the message/disposition-notification
+	// part when parsed. It is not used when writing.
+	Fields textproto.MIMEHeader
+
+	// OriginalHeader is the header of the original message, sent as a
+	// text/rfc822-headers part. It is optional.
+	OriginalHeader textproto.MIMEHeader
+}
+
+func (dn *DispositionNotification) disposition() string {
+	s := dn.ActionMode + "/" + dn.SendingMode + "; " + dn.DispositionType
+	if len(dn.Modifiers) > 0 {
+		s += "/" + strings.Join(dn.Modifiers, ",")
+	}
+	return s
+}
+
+func (dn *DispositionNotification) parseDisposition(v string) {
+	i := strings.IndexByte(v, ';')
+	if i == -1 {
+		dn.DispositionType = strings.ToLower(strings.TrimSpace(v))
+		return
+	}
+	mode, typ := v[:i], v[i+1:]
+	if j := strings.IndexByte(mode, '/'); j != -1 {
+		dn.ActionMode = strings.TrimSpace(mode[:j])
+		dn.SendingMode = strings.TrimSpace(mode[j+1:])
+	} else {
+		dn.ActionMode = strings.TrimSpace(mode)
+	}
+	if j := strings.IndexByte(typ, '/'); j != -1 {
+		for _, m := range strings.Split(typ[j+1:], ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				dn.Modifiers = append(dn.Modifiers, m)
+			}
+		}
+		typ = typ[:j]
+	}
+	dn.DispositionType = strings.ToLower(strings.TrimSpace(typ))
+}
+
+// ReadDispositionNotification reads the remaining parts of r, which
+// should be the body of a multipart/report message with a report-type
+// of disposition-notification.
+func (r *Reader) ReadDispositionNotification() (*DispositionNotification, error) {
+	var dn *DispositionNotification
+	var text string
+	var orig textproto.MIMEHeader
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		typ, _, err := parseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			continue
+		}
+		switch {
+		case dn == nil && (typ == MessageDispositionNotification ||
+			typ == "message/global-disposition-notification"):
+			m, err := ReadMessage(p)
+			if m == nil {
+				return nil, err
+			}
+			dn = newDispositionNotification(m.Header)
+		case orig == nil && (typ == "text/rfc822-headers" || typ == "text/global-headers"):
+			if m, _ := ReadMessage(p); m != nil {
+				orig = m.Header
+			}
+		case orig == nil && (typ == MessageRFC822 || typ == MessageGlobal):
+			if m, _ := p.Message(); m != nil {
+				orig = m.Header
+			}
+		case text == "" && (typ == "" || typ == "text/plain"):
+			var b bytes.Buffer
+			io.CopyN(&b, p, maxBounceText)
+			text = b.String()
+		}
+	}
+	if dn == nil {
+		return nil, ErrNoDispositionNotification
+	}
+	dn.Text = text
+	dn.OriginalHeader = orig
+	return dn, nil
+}
+
+func newDispositionNotification(h textproto.MIMEHeader) *DispositionNotification {
+	dn := &DispositionNotification{
+		ReportingUA:       strings.TrimSpace(h.Get("Reporting-Ua")),
+		MDNGateway:        typedValue(h.Get("Mdn-Gateway")),
+		OriginalRecipient: typedValue(h.Get("Original-Recipient")),
+		FinalRecipient:    typedValue(h.Get("Final-Recipient")),
+		OriginalMessageID: strings.TrimSpace(h.Get("Original-Message-Id")),
+		Error:             strings.TrimSpace(h.Get("Error")),
+		Fields:            h,
+	}
+	dn.parseDisposition(h.Get("Disposition"))
+	return dn
+}
+
+// ReportContentType returns the Content-Type for a multipart/report
+// of the given report type with this Writer's Boundary.
+func (w *Writer) ReportContentType(reportType string) string {
+	return "multipart/report; report-type=" + reportType + "; boundary=" + w.boundary
+}
+
+// WriteDispositionNotification writes dn as the parts of a
+// multipart/report: the human-readable text, the
+// message/disposition-notification fields and, if dn.OriginalHeader is
+// set, the original header. The message's Content-Type should be
+// w.ReportContentType("disposition-notification"). The caller must
+// still Close w.
+func (w *Writer) WriteDispositionNotification(dn *DispositionNotification) error {
+	if dn.FinalRecipient == "" || dn.DispositionType == "" {
+		return errors.New("multipart: disposition notification needs FinalRecipient and DispositionType")
+	}
+	// the fields are checked like part header fields, as the original
+	// header in particular usually comes from untrusted mail
+	var b bytes.Buffer
+	var ferr error
+	field := func(b *bytes.Buffer, k, v string) {
+		if v == "" || ferr != nil {
+			return
+		}
+		if !isFieldName(k) {
+			ferr = &HeaderError{Key: k, Reason: "not printable ASCII without colon"}
+			return
+		}
+		if v, ferr = checkValue(k, v, w.policy); ferr == nil {
+			fmt.Fprintf(b, "%s: %s%s", k, v, w.nl)
+		}
+	}
+	field(&b, "Reporting-UA", dn.ReportingUA)
+	field(&b, "MDN-Gateway", dn.MDNGateway)
+	field(&b, "Original-Recipient", withAddressType(dn.OriginalRecipient))
+	field(&b, "Final-Recipient", withAddressType(dn.FinalRecipient))
+	field(&b, "Original-Message-ID", dn.OriginalMessageID)
+	d := *dn
+	if d.ActionMode == "" {
+		d.ActionMode = "manual-action"
+	}
+	if d.SendingMode == "" {
+		d.SendingMode = "MDN-sent-manually"
+	}
+	field(&b, "Disposition", d.disposition())
+	field(&b, "Error", dn.Error)
+
+	var orig bytes.Buffer
+	keys := make([]string, 0, len(dn.OriginalHeader))
+	for k := range dn.OriginalHeader {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range dn.OriginalHeader[k] {
+			field(&orig, k, v)
+		}
+	}
+	if ferr != nil {
+		return ferr
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	p, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(p, dn.Text); err != nil {
+		return err
+	}
+	h = make(textproto.MIMEHeader)
+	h.Set("Content-Type", MessageDispositionNotification)
+	if p, err = w.CreatePart(h); err != nil {
+		return err
+	}
+	if _, err := p.Write(b.Bytes()); err != nil {
+		return err
+	}
+
+	if dn.OriginalHeader == nil {
+		return nil
+	}
+	h = make(textproto.MIMEHeader)
+	h.Set("Content-Type", "text/rfc822-headers")
+	if p, err = w.CreatePart(h); err != nil {
+		return err
+	}
+	_, err = p.Write(orig.Bytes())
+	return err
+}
+
+// withAddressType prefixes a bare address with the rfc822 address type.
+func withAddressType(addr string) string {
+	if addr == "" || strings.IndexByte(addr, ';') != -1 {
+		return addr
+	}
+	return "rfc822; " + addr
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cention-sany/net/textproto"
+)
+
+func TestDispositionNotificationRoundTrip(t *testing.T) {
+	in := &DispositionNotification{
+		Text:              "Your message was displayed.",
+		ReportingUA:       "mua.example.com; Example Mail",
+		FinalRecipient:    "reader@example.org",
+		OriginalMessageID: "<orig@example.com>",
+		DispositionType:   "displayed",
+		OriginalHeader: textproto.MIMEHeader{
+			"Subject":    {"hello"},
+			"Message-Id": {"<orig@example.com>"},
+		},
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteDispositionNotification(in); err != nil {
+		t.Fatalf("WriteDispositionNotification: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.ReportContentType("disposition-notification"); !strings.HasPrefix(ct,
+		"multipart/report; report-type=disposition-notification; boundary=") {
+		t.Errorf("ReportContentType = %q", ct)
+	}
+	if !strings.Contains(buf.String(), "Final-Recipient: rfc822; reader@example.org\r\n") {
+		t.Errorf("missing Final-Recipient in:\n%s", buf.String())
+	}
+
+	out, err := NewReader(&buf, w.Boundary()).ReadDispositionNotification()
+	if err != nil {
+		t.Fatalf("ReadDispositionNotification: %v", err)
+	}
+	if out.Text != in.Text || out.ReportingUA != in.ReportingUA ||
+		out.FinalRecipient != in.FinalRecipient ||
+		out.OriginalMessageID != in.OriginalMessageID {
+		t.Errorf("got %+v", out)
+	}
+	if out.ActionMode != "manual-action" || out.SendingMode != "MDN-sent-manually" ||
+		out.DispositionType != "displayed" || out.Modifiers != nil {
+		t.Errorf("disposition = %q %q %q %q", out.ActionMode, out.SendingMode,
+			out.DispositionType, out.Modifiers)
+	}
+	if !reflect.DeepEqual(out.OriginalHeader, in.OriginalHeader) {
+		t.Errorf("OriginalHeader = %v; want %v", out.OriginalHeader, in.OriginalHeader)
+	}
+}
+
+func TestDispositionNotificationInjection(t *testing.T) {
+	tests := []*DispositionNotification{
+		{FinalRecipient: "a@x\r\nDisposition: forged", DispositionType: "displayed"},
+		{FinalRecipient: "a@x", DispositionType: "displayed",
+			OriginalHeader: textproto.MIMEHeader{"Subject": {"hi\r\nBcc: b@x"}}},
+		{FinalRecipient: "a@x", DispositionType: "displayed",
+			OriginalHeader: textproto.MIMEHeader{"Bcc: b@x\r\nSubject": {"hi"}}},
+	}
+	for _, dn := range tests {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		err := w.WriteDispositionNotification(dn)
+		if _, ok := err.(*HeaderError); !ok {
+			t.Errorf("%+v: error %v; want *HeaderError", dn, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%+v: wrote %q", dn, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetLineEnding("\n")
+	w.WriteDispositionNotification(&DispositionNotification{FinalRecipient: "a@x", DispositionType: "displayed"})
+	if strings.Contains(buf.String(), "\r") {
+		t.Errorf("CR written with SetLineEnding(\"\\n\"): %q", buf.String())
+	}
+}
+
+func TestParseDisposition(t *testing.T) {
+	var dn DispositionNotification
+	dn.parseDisposition("automatic-action/MDN-sent-automatically; Deleted/error, expired")
+	if dn.ActionMode != "automatic-action" || dn.SendingMode != "MDN-sent-automatically" ||
+		dn.DispositionType != "deleted" ||
+		!reflect.DeepEqual(dn.Modifiers, []string{"error", "expired"}) {
+		t.Errorf("got %+v", dn)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/cention-sany/mime"
+	"github.com/cention-sany/mime/quotedprintable"
+	"github.com/cention-sany/net/textproto"
+)
+
+// Media types of parts that carry an embedded message (or, for
+// message/delivery-status, header-like field groups) as their body.
+const (
+	MessageRFC822         = "message/rfc822"
+	MessageGlobal         = "message/global"          // RFC 6532, UTF-8 headers
+	MessageDeliveryStatus = "message/delivery-status" // RFC 3464
+)
+
+// ErrNotMessage is returned by Part.Message when the part's
+// Content-Type is not one of the message media types.
+var ErrNotMessage = errors.New("multipart: part is not a message")
+
+// ErrNotMultipart is returned by Message.MultipartReader and
+// Part.MultipartReader when the Content-Type is not multipart or has no
+// boundary.
+var ErrNotMultipart = errors.New("multipart: not a multipart entity")
+
+// IsMessageMediaType reports whether mediatype, as returned by
+// mime.ParseMediaType, is one of the embedded message types recognised
+// by this package: message/rfc822, message/global or
+// message/delivery-status.
+func IsMessageMediaType(mediatype string) bool {
+	switch strings.ToLower(mediatype) {
+	case MessageRFC822, MessageGlobal, MessageDeliveryStatus:
+		return true
+	}
+	return false
+}
+
+// parseMediaType is mime.ParseMediaType that ignores the errors the
+// lossy parser can recover from.
+func parseMediaType(v string) (string, map[string]string, error) {
+	typ, params, err := mime.ParseMediaType(v)
+	if err != nil && mime.IsOkPMTError(err) != nil {
+		return "", nil, err
+	}
+	return typ, params, nil
+}
+
+// IsMessage reports whether p's Content-Type is message/rfc822,
+// message/global or message/delivery-status.
+func (p *Part) IsMessage() bool {
+	typ, _, err := parseMediaType(p.Header.Get("Content-Type"))
+	return err == nil && IsMessageMediaType(typ)
+}
+
+// Message parses the body of p as an embedded message. Only the header
+// of the embedded message is read; its body is streamed from p as the
+// returned Message's Body is read.
+//
+// For message/delivery-status parts, the returned Header holds the
+// per-message fields and Body the remaining per-recipient groups.
+func (p *Part) Message() (*Message, error) {
+	if !p.IsMessage() {
+		return nil, ErrNotMessage
+	}
+	var r io.Reader = p
+	// RFC 6532 allows message/global to be transfer encoded, unlike
+	// message/rfc822. quoted-printable is already decoded by p.
+	if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	m, err := ReadMessage(r)
+	if m != nil {
+		m.part = p
+	}
+	return m, err
+}
+
+// A Message is an RFC 5322 (or RFC 6532) message: a header followed
+// by a body.
+type Message struct {
+	// Header is the message header, with the keys canonicalized as
+	// for Part.Header. A quoted-printable Content-Transfer-Encoding
+	// is hidden and the Body transparently decoded, as for Part.
+	Header textproto.MIMEHeader
+
+	// Body reads the message body. It is backed by the reader passed
+	// to ReadMessage and is not buffered in full.
+	Body io.Reader
+
+	part *Part // the message was read from, if any
+}
+
+// ReadMessage reads the header of the message in r and returns a
+// Message whose Body reads the rest of r. The header may contain
+// UTF-8 as permitted by RFC 6532.
+//
+// Like NextPart, a malformed header still returns the Message along
+// with the error so its content can be retained.
+func ReadMessage(r io.Reader) (*Message, error) {
+	br := bufio.NewReaderSize(r, peekBufferSize)
+	header, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err == io.EOF && len(header) > 0 {
+		// header only, such as text/rfc822-headers
+		err = nil
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "malformed MIME header") {
+		return nil, err
+	}
+	m := &Message{Header: header, Body: br}
+	const cte = "Content-Transfer-Encoding"
+	if strings.EqualFold(m.Header.Get(cte), "quoted-printable") {
+		m.Header.Del(cte)
+		m.Body = quotedprintable.NewReader(m.Body)
+	}
+	return m, err
+}
+
+// MediaType returns the parsed Content-Type of the message. A message
+// without a Content-Type is text/plain as defined by RFC 2045.
+func (m *Message) MediaType() (string, map[string]string, error) {
+	v := m.Header.Get("Content-Type")
+	if v == "" {
+		return "text/plain", map[string]string{"charset": "us-ascii"}, nil
+	}
+	return parseMediaType(v)
+}
+
+// MultipartReader returns a Reader over the parts of the message body
+// if the message is multipart. Parts that are themselves messages can
+// be parsed with Part.Message, to any depth. For a message from
+// Part.Message, the Reader inherits the settings and limits of the
+// Reader the part was read from, as for Part.MultipartReader.
+func (m *Message) MultipartReader() (*Reader, error) {
+	typ, params, err := m.MediaType()
+	if err != nil {
+		return nil, err
+	}
+	boundary := params["boundary"]
+	if !strings.HasPrefix(typ, "multipart/") || boundary == "" {
+		return nil, ErrNotMultipart
+	}
+	p := m.part
+	if p == nil {
+		return NewReader(m.Body, boundary), nil
+	}
+	if err := p.mr.checkDepth(); err != nil {
+		return nil, err
+	}
+	// offsets are only meaningful if the body is not decoded
+	var base int64
+	if br, ok := m.Body.(*bufio.Reader); ok && p.r == (partReader{p}) &&
+		!strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+		base = p.offsets.BodyStart + int64(p.bytesRead) - int64(br.Buffered())
+	}
+	return p.mr.nested(m.Body, boundary, base), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const forwardedBody = `--outer
+Content-Type: text/plain
+
+See the forwarded mail.
+--outer
+Content-Type: message/rfc822
+
+From: a@example.com
+Subject: inner
+Content-Type: multipart/alternative; boundary=inner
+
+--inner
+Content-Type: text/plain
+
+plain body
+--inner
+Content-Type: text/html
+
+<b>html body</b>
+--inner--
+--outer
+Content-Type: message/global
+Content-Transfer-Encoding: base64
+
+U3ViamVjdDog5pel5pys6KqeDQoNCmJvZHkNCg==
+--outer--
+`
+
+func TestPartMessage(t *testing.T) {
+	body := strings.Replace(forwardedBody, "\n", "\r\n", -1)
+	mr := NewReader(strings.NewReader(body), "outer")
+
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("first part: %v", err)
+	}
+	if p.IsMessage() {
+		t.Error("text/plain part reported as message")
+	}
+	if _, err := p.Message(); err != ErrNotMessage {
+		t.Errorf("Message() on text/plain = %v; want ErrNotMessage", err)
+	}
+
+	p, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("second part: %v", err)
+	}
+	if !p.IsMessage() {
+		t.Fatal("message/rfc822 part not reported as message")
+	}
+	m, err := p.Message()
+	if err != nil {
+		t.Fatalf("Message(): %v", err)
+	}
+	if g, e := m.Header.Get("Subject"), "inner"; g != e {
+		t.Errorf("Subject = %q; want %q", g, e)
+	}
+	inner, err := m.MultipartReader()
+	if err != nil {
+		t.Fatalf("MultipartReader(): %v", err)
+	}
+	for _, want := range []string{"plain body", "<b>html body</b>"} {
+		ip, err := inner.NextPart()
+		if err != nil {
+			t.Fatalf("inner part %q: %v", want, err)
+		}
+		b, err := ioutil.ReadAll(ip)
+		if err != nil || string(b) != want {
+			t.Errorf("inner part = %q, %v; want %q", b, err, want)
+		}
+	}
+
+	p, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("third part: %v", err)
+	}
+	m, err = p.Message()
+	if err != nil {
+		t.Fatalf("Message() on message/global: %v", err)
+	}
+	if g, e := m.Header.Get("Subject"), "日本語"; g != e {
+		t.Errorf("Subject = %q; want %q", g, e)
+	}
+	if _, err := m.MultipartReader(); err != ErrNotMultipart {
+		t.Errorf("MultipartReader() = %v; want ErrNotMultipart", err)
+	}
+	b, _ := ioutil.ReadAll(m.Body)
+	if g, e := string(b), "body\r\n"; g != e {
+		t.Errorf("body = %q; want %q", g, e)
+	}
+}
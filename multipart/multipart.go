@@ -15,6 +15,8 @@ package multipart
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -46,9 +48,11 @@ type Part struct {
 	// during Read calls.
 	Header textproto.MIMEHeader
 
-	buffer    *bytes.Buffer
 	mr        *Reader
 	bytesRead int
+	safe      int  // buffered bytes known to be body
+	last      bool // the body ends after the safe bytes
+	offsets   PartOffsets
 
 	disposition       string
 	dispositionParams map[string]string
@@ -74,12 +78,17 @@ func (p *Part) FormName() string {
 }
 
 // FileName returns the filename parameter of the Part's
-// Content-Disposition header.
+// Content-Disposition header, normalized if the Reader is in the
+// FileNameNormalized mode.
 func (p *Part) FileName() string {
 	if p.dispositionParams == nil {
 		p.parseContentDisposition()
 	}
-	return p.dispositionParams["filename"]
+	name := p.dispositionParams["filename"]
+	if p.mr != nil && p.mr.fileNameMode == FileNameNormalized && name != "" {
+		name = NormalizeFileName(name)
+	}
+	return name
 }
 
 func (p *Part) parseContentDisposition() {
@@ -105,25 +114,59 @@ func NewCorrectUTF8QPReader(r io.Reader, boundary string) *Reader {
 	return newReader(r, boundary, true)
 }
 
+// minBufferSize is the smallest buffer SetBufferSize allows. It holds a
+// delimiter line with the longest boundary and some whitespace.
+const minBufferSize = 256
+
+// SetBufferSize sets the size of the buffer the Reader scans for
+// delimiters, 4096 bytes by default. A larger buffer makes reading large
+// parts faster. Sizes below 256 bytes are raised to it. It must be
+// called before anything is read.
+func (r *Reader) SetBufferSize(size int) error {
+	if r.src.n != 0 {
+		return errors.New("multipart: SetBufferSize called after reading")
+	}
+	if size < minBufferSize {
+		size = minBufferSize
+	}
+	r.bufReader = bufio.NewReaderSize(r.src, size)
+	r.bufSize = size
+	return nil
+}
+
 func newReader(r io.Reader, boundary string, correctUTF8qp bool) *Reader {
-	b := []byte("\r\n--" + boundary + "--")
-	return &Reader{
-		bufReader:        bufio.NewReaderSize(r, peekBufferSize),
-		correctUTF8qp:    correctUTF8qp,
-		nl:               b[:2],
-		nlDashBoundary:   b[:len(b)-2],
-		dashBoundaryDash: b[2:],
-		dashBoundary:     b[2 : len(b)-2],
+	src := &countReader{r: r}
+	mr := &Reader{
+		src:           src,
+		bufReader:     bufio.NewReaderSize(src, peekBufferSize),
+		bufSize:       peekBufferSize,
+		correctUTF8qp: correctUTF8qp,
+		repairs:       new([]Repair),
+		limits:        new(limitState),
 	}
+	mr.setBoundary(boundary)
+	return mr
+}
+
+func (mr *Reader) setBoundary(boundary string) {
+	b := []byte("\r\n--" + boundary + "--")
+	mr.boundary = boundary
+	mr.nl = b[:2]
+	mr.nlDashBoundary = b[:len(b)-2]
+	mr.dashBoundaryDash = b[2:]
+	mr.dashBoundary = b[2 : len(b)-2]
+	mr.finder.reset(mr.nlDashBoundary)
 }
 
 func newPart(mr *Reader) (*Part, error) {
 	bp := &Part{
 		Header: make(map[string][]string),
 		mr:     mr,
-		buffer: new(bytes.Buffer),
 	}
+	bp.offsets.HeaderStart = mr.offset()
+	bp.offsets.BodyEnd = -1
 	err := bp.populateHeaders()
+	bp.offsets.BodyStart = mr.offset()
 	bp.r = partReader{bp}
 	const cte = "Content-Transfer-Encoding"
 	if bp.Header.Get(cte) == "quoted-printable" {
@@ -150,7 +193,15 @@ func newPart(mr *Reader) (*Part, error) {
 }
 
 func (bp *Part) populateHeaders() error {
-	r := textproto.NewReader(bp.mr.bufReader)
+	br := bp.mr.bufReader
+	if o := bp.mr.opts; o.MaxHeaderBytes > 0 || o.MaxHeaderLines > 0 {
+		b, err := bp.mr.readHeaderBlock()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		br = bufio.NewReader(bytes.NewReader(b))
+	}
+	r := textproto.NewReader(br)
 	header, err := r.ReadMIMEHeader()
 	bp.Header = header
 	return err
@@ -173,12 +224,41 @@ func (pr partReader) Read(d []byte) (n int, err error) {
 	defer func() {
 		p.bytesRead += n
 	}()
-	if p.buffer.Len() >= len(d) {
-		// Internal buffer of unconsumed data is large enough for
-		// the read request.  No need to parse more at the moment.
-		return p.buffer.Read(d)
+	for p.safe == 0 {
+		if p.last {
+			if p.offsets.BodyEnd < 0 {
+				p.offsets.BodyEnd = p.mr.offset()
+			}
+			return 0, io.EOF
+		}
+		if err := p.scan(); err != nil {
+			return 0, err
+		}
+	}
+	if max := p.mr.opts.MaxPartSize; max > 0 {
+		if int64(p.bytesRead) >= max {
+			return 0, p.mr.stop(&LimitError{Limit: "MaxPartSize", Max: max})
+		}
+		if rest := max - int64(p.bytesRead); int64(len(d)) > rest {
+			d = d[:rest]
+		}
 	}
-	peek, err := p.mr.bufReader.Peek(peekBufferSize) // TODO(bradfitz): add buffer size accessor
+	if len(d) > p.safe {
+		d = d[:p.safe]
+	}
+	// the safe bytes are buffered, so this copies straight from the
+	// buffer of bufReader into d
+	n, err = p.mr.bufReader.Read(d)
+	p.safe -= n
+	return n, err
+}
+
+// scan looks for the end of the body in the buffer of the Reader and
+// sets how many of the buffered bytes can be returned by Read without
+// looking at them again. Only the tail of the buffer that might hold
+// the start of a delimiter is scanned again, after more input is read.
+func (p *Part) scan() error {
+	peek, err := p.mr.bufReader.Peek(p.mr.bufSize)
 
 	// Look for an immediate empty part without a leading \r\n
 	// before the boundary separator.  Some MIME code makes empty
@@ -186,51 +266,44 @@ func (pr partReader) Read(d []byte) (n int, err error) {
 	// before the subsequent boundary even for empty parts and
 	// won't hit this path.
 	if p.bytesRead == 0 && p.mr.peekBufferIsEmptyPart(peek) {
-		return 0, io.EOF
+		p.last = true
+		return nil
 	}
 	noMoreData := err == io.EOF
+	if isStopErr(err) {
+		return p.mr.stop(err)
+	}
 	if err != nil && err != io.EOF {
-		return 0, fmt.Errorf("multipart: Part Read: %v", err)
-	}
-	if peek == nil {
-		panic("nil peek buf")
-	}
-	// Search the peek buffer for "\r\n--boundary". If found,
-	// consume everything up to the boundary. If not, consume only
-	// as much of the peek buffer as cannot hold the boundary
-	// string.
-	nCopy := 0
-	foundBoundary := false
-	if idx, isEnd := p.mr.peekBufferSeparatorIndex(peek); idx != -1 {
-		nCopy = idx
-		foundBoundary = isEnd
-		if !isEnd && nCopy == 0 {
-			nCopy = 1 // make some progress.
+		return fmt.Errorf("multipart: Part Read: %v", err)
+	}
+	// Search the peek buffer for "\r\n--boundary". If found, the
+	// body ends there. If not, everything in the peek buffer but
+	// what could hold the start of the boundary string is body.
+	if idx, isEnd := p.mr.separatorIndex(peek); idx != -1 {
+		p.safe = idx
+		p.last = isEnd
+		if !isEnd && idx == 0 {
+			p.safe = 1 // make some progress.
 		}
 	} else if noMoreData {
-		// when no more data found and no boundary marker, then we should
-		// copy all data into p.buffer
-		nCopy = len(peek)
+		p.safe = len(peek)
+		p.last = true
 	} else if safeCount := len(peek) - len(p.mr.nlDashBoundary); safeCount > 0 {
-		// we reserve a at least len(p.mr.nlDashBoundary) to ensure we
-		// get a complete boundary marker (not partially cut)
-		nCopy = safeCount
+		p.safe = safeCount
 	}
-	if nCopy > 0 {
-		if _, err := io.CopyN(p.buffer, p.mr.bufReader, int64(nCopy)); err != nil {
-			return 0, err
-		}
-	}
-	n, err = p.buffer.Read(d)
-	if err == io.EOF && !noMoreData && !foundBoundary {
-		// If the boundary hasn't been reached there's more to
-		// read, so don't pass through an EOF from the buffer
-		err = nil
-	}
-	return
+	return nil
 }
 
+// Close reads the rest of the part so that the Reader can go on to the
+// next one, unless SetDrainOnClose(false) was called: the Reader is then
+// abandoned if p was not read to its end.
 func (p *Part) Close() error {
+	if p.mr.noDrain {
+		if !p.atEnd() {
+			p.mr.stop(ErrAbandoned)
+		}
+		return nil
+	}
 	io.Copy(ioutil.Discard, p)
 	return nil
 }
@@ -240,16 +313,38 @@ func (p *Part) Close() error {
 // isn't supported.
 type Reader struct {
 	bufReader *bufio.Reader
+	bufSize   int          // size of bufReader, see SetBufferSize
+	src       *countReader // under bufReader
+	base      int64        // offset of the input in the outermost input
 
 	currentPart *Part
 	partsRead   int
 
 	correctUTF8qp bool
-
-	nl               []byte // "\r\n" or "\n" (set after seeing first boundary line)
-	nlDashBoundary   []byte // nl + "--boundary"
-	dashBoundaryDash []byte // "--boundary--"
-	dashBoundary     []byte // "--boundary"
+	fileNameMode  FileNameMode
+
+	boundary string
+	done     bool      // final boundary or end of input seen
+	checking bool      // in CheckNextPart, don't record repairs
+	recover  bool      // see SetRecovery
+	repairs  *[]Repair // shared with nested readers
+
+	opts    ReaderOptions
+	limits  *limitState     // shared with nested readers
+	ctx     context.Context // see SetContext
+	noDrain bool            // see SetDrainOnClose
+	depth   int             // of nesting in Part.MultipartReader
+
+	preamble     bytes.Buffer
+	wantPreamble bool // Preamble was called before the first part
+	pending      bool // currentPart and pendingErr read ahead by Preamble
+	pendingErr   error
+
+	nl               []byte    // "\r\n" or "\n" (set after seeing first boundary line)
+	nlDashBoundary   []byte    // nl + "--boundary"
+	dashBoundaryDash []byte    // "--boundary--"
+	dashBoundary     []byte    // "--boundary"
+	finder           sepFinder // of nlDashBoundary
 }
 
 // This will allow whether NextPart is a valid part or not.
@@ -257,6 +352,8 @@ type Reader struct {
 // the internally whether the NextPart is valid or not but it can
 // tell whether next part is EOF or not.
 func (r *Reader) CheckNextPart() error {
+	r.checking = true
+	defer func() { r.checking = false }()
 	expectNewPart := false
 	n := r.bufReader.Buffered()
 	nb, err := r.bufReader.Peek(n)
@@ -306,10 +403,24 @@ func (r *Reader) CheckNextPart() error {
 // NextPart returns the next part in the multipart or an error.
 // When there are no more parts, the error io.EOF is returned.
 func (r *Reader) NextPart() (*Part, error) {
+	if r.pending {
+		// read ahead by Preamble
+		r.pending = false
+		return r.currentPart, r.pendingErr
+	}
 	if r.currentPart != nil {
 		r.currentPart.Close()
 	}
+	if r.limits.err == nil && r.ctx != nil {
+		r.stop(r.ctx.Err())
+	}
+	if r.limits.err != nil {
+		return nil, r.limits.err
+	}
 
+	if r.done {
+		return nil, io.EOF
+	}
 	expectNewPart := false
 	for {
 		line, err := r.bufReader.ReadSlice('\n')
@@ -319,15 +430,32 @@ func (r *Reader) NextPart() (*Part, error) {
 			// (since it's missing the '\n'), but this is a valid
 			// multipart EOF so we need to return io.EOF instead of
 			// a fmt-wrapped one.
+			if r.partsRead == 0 && !r.isFinalBoundary(line) {
+				r.keepPreamble(line)
+			}
+			if r.recover && r.partsRead > 0 && !r.isFinalBoundary(line) {
+				r.repair(RepairMissingFinalBoundary, line)
+			}
+			r.done = true
 			return nil, io.EOF
 		}
+		if isStopErr(err) {
+			return nil, r.stop(err)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("multipart: NextPart: %v", err)
 		}
 
 		if r.isBoundaryDelimiterLine(line) {
+			if max := r.opts.MaxParts; max > 0 && r.limits.parts >= max {
+				return nil, r.stop(&LimitError{Limit: "MaxParts", Max: int64(max)})
+			}
+			r.limits.parts++
 			r.partsRead++
 			bp, err := newPart(r)
+			if isStopErr(err) {
+				return nil, r.stop(err)
+			}
 			if err != nil {
 				if strings.HasPrefix(err.Error(), "malformed MIME header") {
 					// retain the content even header is malformed
@@ -342,15 +470,22 @@ func (r *Reader) NextPart() (*Part, error) {
 
 		if r.isFinalBoundary(line) {
 			// Expected EOF
+			r.done = true
 			return nil, io.EOF
 		}
 
 		if expectNewPart {
+			if r.recover {
+				r.repair(RepairSkippedLine, line)
+				expectNewPart = false
+				continue
+			}
 			return nil, fmt.Errorf("multipart: expecting a new Part; got line %q", string(line))
 		}
 
 		if r.partsRead == 0 {
-			// skip line
+			// skip line, keeping it for Preamble
+			r.keepPreamble(line)
 			continue
 		}
 
@@ -358,11 +493,15 @@ func (r *Reader) NextPart() (*Part, error) {
 		// body of the previous part and the boundary line we
 		// now expect will follow. (either a new part or the
 		// end boundary)
-		if bytes.Equal(line, r.nl) {
+		if bytes.Equal(line, r.nl) || r.recover && isNewline(line) {
 			expectNewPart = true
 			continue
 		}
 
+		if r.recover {
+			r.repair(RepairSkippedLine, line)
+			continue
+		}
 		return nil, fmt.Errorf("multipart: unexpected line in Next(): %q", line)
 	}
 }
@@ -372,11 +511,11 @@ func (r *Reader) NextPart() (*Part, error) {
 // It matches `^--boundary--[ \t]*(\r\n)?$`
 func (mr *Reader) isFinalBoundary(line []byte) bool {
 	if !bytes.HasPrefix(line, mr.dashBoundaryDash) {
-		return false
+		return mr.recover && mr.isBareFinalBoundary(line)
 	}
 	rest := line[len(mr.dashBoundaryDash):]
 	rest = skipLWSPChar(rest)
-	return len(rest) == 0 || bytes.Equal(rest, mr.nl)
+	return len(rest) == 0 || bytes.Equal(rest, mr.nl) || mr.recover && isNewline(rest)
 }
 
 func (mr *Reader) isBoundaryDelimiterLine(line []byte) (ret bool) {
@@ -387,7 +526,7 @@ func (mr *Reader) isBoundaryDelimiterLine(line []byte) (ret bool) {
 	//   value from the Content-Type header field, optional linear
 	//   whitespace, and a terminating CRLF.
 	if !bytes.HasPrefix(line, mr.dashBoundary) {
-		return false
+		return mr.recover && mr.isBareBoundaryLine(line)
 	}
 	rest := line[len(mr.dashBoundary):]
 	rest = skipLWSPChar(rest)
@@ -398,6 +537,11 @@ func (mr *Reader) isBoundaryDelimiterLine(line []byte) (ret bool) {
 	if mr.partsRead == 0 && len(rest) == 1 && rest[0] == '\n' {
 		mr.nl = mr.nl[1:]
 		mr.nlDashBoundary = mr.nlDashBoundary[1:]
+		mr.finder.reset(mr.nlDashBoundary)
+	}
+	if mr.recover && !bytes.Equal(rest, mr.nl) && isNewline(rest) {
+		mr.repair(RepairBoundaryNewline, line)
+		return true
 	}
 	return bytes.Equal(rest, mr.nl)
 }
@@ -434,29 +578,42 @@ func (mr *Reader) peekBufferIsEmptyPart(peek []byte) bool {
 // unrelated separator). To be the end, the peek buffer must contain a
 // newline after the boundary or contain the ending boundary (--separator--).
 func (mr *Reader) peekBufferSeparatorIndex(peek []byte) (idx int, isEnd bool) {
-	idx = bytes.Index(peek, mr.nlDashBoundary)
+	idx = mr.finder.index(peek)
+	if idx == -1 {
+		return
+	}
+	return idx, isSeparatorEnd(peek[idx+len(mr.nlDashBoundary):])
+}
+
+func peekSeparatorIndex(peek, sep []byte) (idx int, isEnd bool) {
+	idx = bytes.Index(peek, sep)
 	if idx == -1 {
 		return
 	}
-	peek = peek[idx+len(mr.nlDashBoundary):]
+	return idx, isSeparatorEnd(peek[idx+len(sep):])
+}
+
+// isSeparatorEnd reports whether peek, following a separator, completes
+// a delimiter line.
+func isSeparatorEnd(peek []byte) bool {
 	if len(peek) == 0 || len(peek) == 1 && peek[0] == '-' {
-		return idx, false
+		return false
 	}
 	if len(peek) > 1 && peek[0] == '-' && peek[1] == '-' {
-		return idx, true
+		return true
 	}
 	peek = skipLWSPChar(peek)
 	// Don't have a complete line after the peek.
 	if bytes.IndexByte(peek, '\n') == -1 {
-		return idx, false
+		return false
 	}
 	if len(peek) > 0 && peek[0] == '\n' {
-		return idx, true
+		return true
 	}
 	if len(peek) > 1 && peek[0] == '\r' && peek[1] == '\n' {
-		return idx, true
+		return true
 	}
-	return idx, false
+	return false
 }
 
 // skipLWSPChar returns b with leading spaces and tabs removed.
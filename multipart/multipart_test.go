@@ -813,3 +813,43 @@ func roundTripParseTest() parseTest {
 	t.sep = w.Boundary()
 	return t
 }
+
+func benchmarkReadPart(b *testing.B, boundary string, size, bufSize int) {
+	body := "--" + boundary + "\r\nContent-Type: application/octet-stream\r\n\r\n" +
+		strings.Repeat(strings.Repeat("QUJD", 19)+"\r\n", size/78) + "\r\n--" + boundary + "--\r\n"
+	buf := make([]byte, 32<<10)
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(body), boundary)
+		if bufSize != 0 {
+			if err := r.SetBufferSize(bufSize); err != nil {
+				b.Fatal(err)
+			}
+		}
+		p, err := r.NextPart()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			_, err := p.Read(buf)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchBoundary is as long as the boundaries Writer makes.
+var benchBoundary = strings.Repeat("0123456789abcdef", 4)[:60]
+
+func BenchmarkReadPart1M(b *testing.B)       { benchmarkReadPart(b, benchBoundary, 1<<20, 0) }
+func BenchmarkReadPart1MBuf64K(b *testing.B) { benchmarkReadPart(b, benchBoundary, 1<<20, 64<<10) }
+func BenchmarkReadPart16M(b *testing.B)      { benchmarkReadPart(b, benchBoundary, 16<<20, 0) }
+func BenchmarkReadPart16MBuf64K(b *testing.B) {
+	benchmarkReadPart(b, benchBoundary, 16<<20, 64<<10)
+}
+func BenchmarkReadPart1MShortBoundary(b *testing.B) { benchmarkReadPart(b, "b", 1<<20, 0) }
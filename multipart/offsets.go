@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import "io"
+
+// PartOffsets locates a part in the input of the outermost Reader: the
+// Reader given the message body, even for parts of a Reader returned by
+// Part.MultipartReader. Offsets count raw bytes, before any
+// Content-Transfer-Encoding decoding.
+type PartOffsets struct {
+	// HeaderStart is the offset of the header block, just after the
+	// delimiter line.
+	HeaderStart int64
+
+	// BodyStart is the offset of the body, just after the blank line
+	// ending the header block.
+	BodyStart int64
+
+	// BodyEnd is the offset just past the body, where the line break
+	// belonging to the next delimiter starts. It is -1 until the body
+	// has been read to its end, which Close and NextPart do.
+	BodyEnd int64
+}
+
+// Offsets returns the location of p in the input.
+func (p *Part) Offsets() PartOffsets {
+	return p.offsets
+}
+
+// countReader counts the bytes read through it, failing with a
+// *LimitError after max bytes if max is positive.
+type countReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (cr *countReader) Read(p []byte) (int, error) {
+	if cr.max <= 0 {
+		n, err := cr.r.Read(p)
+		cr.n += int64(n)
+		return n, err
+	}
+	if rest := cr.max - cr.n + 1; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := cr.r.Read(p)
+	if cr.n+int64(n) > cr.max {
+		n = int(cr.max - cr.n)
+		err = &LimitError{Limit: "MaxTotalSize", Max: cr.max}
+	}
+	cr.n += int64(n)
+	return n, err
+}
+
+// offset returns the offset in the outermost input of the next byte
+// the Reader will consume.
+func (r *Reader) offset() int64 {
+	return r.base + r.src.n - int64(r.bufReader.Buffered())
+}
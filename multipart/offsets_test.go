@@ -0,0 +1,58 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestPartOffsets(t *testing.T) {
+	const body = "preamble\r\n" +
+		"--outer\r\nContent-Type: text/plain\r\n\r\nfirst\r\n" +
+		"--outer\r\nContent-Type: multipart/mixed; boundary=inner\r\n\r\n" +
+		"--inner\r\nX-Inner: 1\r\n\r\nnested body\r\n--inner--\r\n" +
+		"\r\n--outer--\r\n"
+	r := NewReader(strings.NewReader(body), "outer")
+
+	span := func(p *Part) (header, content string) {
+		o := p.Offsets()
+		return body[o.HeaderStart:o.BodyStart], body[o.BodyStart:o.BodyEnd]
+	}
+
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if o := p.Offsets(); o.BodyEnd != -1 {
+		t.Errorf("BodyEnd before reading = %d; want -1", o.BodyEnd)
+	}
+	ioutil.ReadAll(p)
+	if h, c := span(p); h != "Content-Type: text/plain\r\n\r\n" || c != "first" {
+		t.Errorf("first part spans %q, %q", h, c)
+	}
+
+	p, err = r.NextPart()
+	if err != nil {
+		t.Fatalf("second NextPart: %v", err)
+	}
+	mr, err := p.MultipartReader()
+	if err != nil {
+		t.Fatalf("MultipartReader: %v", err)
+	}
+	ip, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("inner NextPart: %v", err)
+	}
+	ioutil.ReadAll(ip)
+	if h, c := span(ip); h != "X-Inner: 1\r\n\r\n" || c != "nested body" {
+		t.Errorf("nested part spans %q, %q", h, c)
+	}
+	p.Close()
+	if _, c := span(p); !strings.HasPrefix(c, "--inner\r\n") || !strings.HasSuffix(c, "--inner--\r\n") {
+		t.Errorf("multipart part body = %q", c)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Preamble returns the bytes before the first delimiter line, which
+// RFC 2046 says to ignore but some mailers use for the only
+// human-readable text. If no part has been read yet, Preamble reads
+// ahead to the first part, which the next call to NextPart returns.
+// Otherwise the preamble was skipped without being kept, and the
+// returned reader is empty. At most the MaxPartSize of ReaderOptions,
+// or 1 MiB if it is not set, are kept.
+func (r *Reader) Preamble() io.Reader {
+	if r.partsRead == 0 && !r.done && !r.pending {
+		r.wantPreamble = true
+		p, err := r.NextPart()
+		r.currentPart, r.pendingErr, r.pending = p, err, true
+	}
+	return bytes.NewReader(r.preamble.Bytes())
+}
+
+// maxPreamble is the size of the preamble kept for Preamble when
+// MaxPartSize is not set.
+const maxPreamble = 1 << 20
+
+// keepPreamble adds line to the preamble if Preamble asked for it and
+// the preamble is not at its limit yet.
+func (r *Reader) keepPreamble(line []byte) {
+	if !r.wantPreamble {
+		return
+	}
+	max := int64(maxPreamble)
+	if r.opts.MaxPartSize > 0 {
+		max = r.opts.MaxPartSize
+	}
+	if left := max - int64(r.preamble.Len()); left < int64(len(line)) {
+		line = line[:left]
+	}
+	r.preamble.Write(line)
+}
+
+var errEpilogueEarly = errors.New("multipart: Epilogue called before the final delimiter was read")
+
+// Epilogue returns a reader of the bytes after the final delimiter line.
+// It reads from the underlying reader, so it is only valid after
+// NextPart has returned io.EOF; before that reading it returns an
+// error. Together with the preamble, the part headers and the raw
+// part content, it allows the body to be reconstructed byte for byte.
+func (r *Reader) Epilogue() io.Reader {
+	if !r.done {
+		return errReader{errEpilogueEarly}
+	}
+	return r.bufReader
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+// SetPreamble sets text to be written before the first delimiter line,
+// for readers that do not understand MIME. A line ending is added if
+// text does not end with one. SetPreamble must be called before any
+// parts are created.
+func (w *Writer) SetPreamble(text string) error {
+	if w.lastpart != nil {
+		return errors.New("mime: SetPreamble called after write")
+	}
+	w.preamble = text
+	return nil
+}
+
+// SetEpilogue sets text to be written after the final delimiter line by
+// Close.
+func (w *Writer) SetEpilogue(text string) {
+	w.epilogue = text
+}
+
+func (w *Writer) writePreamble(b *bytes.Buffer) {
+	if w.preamble == "" {
+		return
+	}
+	b.WriteString(w.preamble)
+	if !strings.HasSuffix(w.preamble, "\n") {
+		b.WriteString(w.nl)
+	}
+	w.preamble = ""
+}
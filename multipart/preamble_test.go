@@ -0,0 +1,78 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestPreambleEpilogue(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetBoundary("b")
+	if err := w.SetPreamble("This is a multi-part message in MIME format."); err != nil {
+		t.Fatal(err)
+	}
+	w.SetEpilogue("bye\r\n")
+	w.WriteField("f", "v")
+	if err := w.SetPreamble("late"); err == nil {
+		t.Error("SetPreamble after write succeeded")
+	}
+	w.Close()
+	const want = "This is a multi-part message in MIME format.\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\nv\r\n--b--\r\nbye\r\n"
+	if g := buf.String(); g != want {
+		t.Fatalf("wrote %q; want %q", g, want)
+	}
+
+	r := NewReader(&buf, "b")
+	if _, err := ioutil.ReadAll(r.Epilogue()); err != errEpilogueEarly {
+		t.Errorf("early Epilogue read = %v; want errEpilogueEarly", err)
+	}
+	pre, _ := ioutil.ReadAll(r.Preamble())
+	if g, e := string(pre), "This is a multi-part message in MIME format.\r\n"; g != e {
+		t.Errorf("Preamble = %q; want %q", g, e)
+	}
+	p, err := r.NextPart()
+	if err != nil || p.FormName() != "f" {
+		t.Fatalf("NextPart after Preamble = %v, %v", p, err)
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Fatalf("final NextPart = %v", err)
+	}
+	epi, err := ioutil.ReadAll(r.Epilogue())
+	if err != nil || string(epi) != "bye\r\n" {
+		t.Errorf("Epilogue = %q, %v", epi, err)
+	}
+}
+
+func TestPreambleKept(t *testing.T) {
+	body := strings.Repeat("preamble line\r\n", 100) + "--b\r\n\r\nx\r\n--b--\r\n"
+
+	r := NewReader(strings.NewReader(body), "b")
+	if _, err := r.NextPart(); err != nil {
+		t.Fatal(err)
+	}
+	if r.preamble.Len() != 0 {
+		t.Errorf("kept %d bytes of preamble without Preamble", r.preamble.Len())
+	}
+	if pre, _ := ioutil.ReadAll(r.Preamble()); len(pre) != 0 {
+		t.Errorf("late Preamble = %q", pre)
+	}
+
+	r = NewReader(strings.NewReader(body), "b")
+	r.SetOptions(ReaderOptions{MaxPartSize: 20})
+	pre, _ := ioutil.ReadAll(r.Preamble())
+	if g, e := string(pre), "preamble line\r\npream"; g != e {
+		t.Errorf("Preamble = %q; want %q", g, e)
+	}
+	if _, err := r.NextPart(); err != nil {
+		t.Errorf("NextPart after capped Preamble: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// RepairKind identifies a defect that a Reader in recovery mode worked
+// around.
+type RepairKind int
+
+const (
+	// RepairBoundaryParameter: whitespace or quotes were trimmed from
+	// the boundary given to the Reader.
+	RepairBoundaryParameter RepairKind = iota
+
+	// RepairBoundaryWithoutDashes: a delimiter line lacked the
+	// leading "--".
+	RepairBoundaryWithoutDashes
+
+	// RepairBoundaryNewline: a delimiter line ended differently
+	// (LF or CRLF) from the first one.
+	RepairBoundaryNewline
+
+	// RepairSkippedLine: an unexpected line between parts was
+	// skipped.
+	RepairSkippedLine
+
+	// RepairMissingFinalBoundary: the body ended without the closing
+	// delimiter, as in a truncated message.
+	RepairMissingFinalBoundary
+
+	// RepairMissingNestedBoundary: a part claimed to be multipart but
+	// had no boundary parameter; its boundary was discovered from its
+	// body or, failing that, it was left as a plain part.
+	RepairMissingNestedBoundary
+)
+
+var repairNames = [...]string{
+	RepairBoundaryParameter:     "boundary parameter trimmed",
+	RepairBoundaryWithoutDashes: "delimiter without leading dashes",
+	RepairBoundaryNewline:       "delimiter with mixed line ending",
+	RepairSkippedLine:           "unexpected line skipped",
+	RepairMissingFinalBoundary:  "missing final delimiter",
+	RepairMissingNestedBoundary: "multipart part without boundary",
+}
+
+func (k RepairKind) String() string {
+	if int(k) < len(repairNames) {
+		return repairNames[k]
+	}
+	return "unknown repair"
+}
+
+// A Repair records a defect worked around by a Reader in recovery mode.
+type Repair struct {
+	Kind RepairKind
+	Part int    // number of parts read when the defect was found
+	Line string // offending line, possibly truncated, if any
+}
+
+// maxRepairLine limits the length of Repair.Line.
+const maxRepairLine = 200
+
+// SetRecovery turns recovery mode on or off. It must be called before
+// the first call to NextPart.
+//
+// In recovery mode the Reader trims whitespace and quotes from its
+// boundary, accepts delimiter lines without the leading "--" or with
+// either line ending, skips unexpected lines between parts instead of
+// failing, and treats the end of input as the end of the last part. As
+// a delimiter line without dashes is just the boundary on a line of
+// its own, recovery mode should only be used on input that failed to
+// parse otherwise. Every defect worked around is recorded and
+// available from Repairs.
+func (r *Reader) SetRecovery(on bool) {
+	r.recover = on
+	if !on {
+		return
+	}
+	if b := strings.Trim(r.boundary, " \t\"'"); b != r.boundary && b != "" {
+		r.repair(RepairBoundaryParameter, []byte(r.boundary))
+		r.setBoundary(b)
+	}
+}
+
+// Repairs returns the defects worked around so far by r and by the
+// Readers returned by the MultipartReader method of its parts.
+func (r *Reader) Repairs() []Repair {
+	return append([]Repair(nil), *r.repairs...)
+}
+
+func (r *Reader) repair(kind RepairKind, line []byte) {
+	if r.checking {
+		return
+	}
+	if len(line) > maxRepairLine {
+		line = line[:maxRepairLine]
+	}
+	*r.repairs = append(*r.repairs, Repair{Kind: kind, Part: r.partsRead, Line: string(line)})
+}
+
+func isNewline(b []byte) bool {
+	return len(b) == 1 && b[0] == '\n' || len(b) == 2 && b[0] == '\r' && b[1] == '\n'
+}
+
+// isBareBoundaryLine reports whether line is the boundary, without the
+// leading dashes, on a line of its own.
+func (mr *Reader) isBareBoundaryLine(line []byte) bool {
+	if !bytes.HasPrefix(line, mr.dashBoundary[2:]) {
+		return false
+	}
+	if !isNewline(skipLWSPChar(line[len(mr.dashBoundary)-2:])) {
+		return false
+	}
+	mr.repair(RepairBoundaryWithoutDashes, line)
+	return true
+}
+
+// isBareFinalBoundary is isBareBoundaryLine for the final boundary.
+func (mr *Reader) isBareFinalBoundary(line []byte) bool {
+	if !bytes.HasPrefix(line, mr.dashBoundaryDash[2:]) {
+		return false
+	}
+	rest := skipLWSPChar(line[len(mr.dashBoundaryDash)-2:])
+	if len(rest) != 0 && !isNewline(rest) {
+		return false
+	}
+	mr.repair(RepairBoundaryWithoutDashes, line)
+	return true
+}
+
+// separatorIndex is peekBufferSeparatorIndex that, in recovery mode,
+// also finds delimiters with a bare LF before them or without the
+// leading dashes.
+func (mr *Reader) separatorIndex(peek []byte) (idx int, isEnd bool) {
+	idx, isEnd = mr.peekBufferSeparatorIndex(peek)
+	if !mr.recover {
+		return
+	}
+	bare := mr.dashBoundary[2:]
+	seps := [...][]byte{
+		append([]byte("\n--"), bare...),
+		append(append([]byte(nil), mr.nl...), bare...),
+		append([]byte("\n"), bare...),
+	}
+	for _, sep := range seps {
+		i, end := peekSeparatorIndex(peek, sep)
+		if i == -1 {
+			continue
+		}
+		if i > 0 && peek[i] == '\n' && peek[i-1] == '\r' {
+			i-- // keep the CR with the delimiter
+		}
+		if idx == -1 || i < idx {
+			idx, isEnd = i, end
+		}
+	}
+	return
+}
+
+// MultipartReader returns a Reader over the parts of p if p is itself
+// multipart. The Reader inherits the settings and limits of the Reader
+// p was read from. In recovery mode the boundary of a multipart part without a
+// boundary parameter is discovered as by DiscoverReader; either way
+// this is recorded as a repair, and if no boundary is found p can
+// still be read as a plain part.
+func (p *Part) MultipartReader() (*Reader, error) {
+	typ, params, err := parseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(typ, "multipart/") {
+		return nil, ErrNotMultipart
+	}
+	if err := p.mr.checkDepth(); err != nil {
+		return nil, err
+	}
+	boundary := params["boundary"]
+	base := p.offsets.BodyStart + int64(p.bytesRead)
+	if boundary == "" {
+		if !p.mr.recover {
+			return nil, ErrNotMultipart
+		}
+		p.mr.repair(RepairMissingNestedBoundary, []byte(p.Header.Get("Content-Type")))
+		// sniff through a buffer that p keeps reading from
+		br := bufio.NewReaderSize(p.r, discoverWindow)
+		p.r = br
+		if boundary, err = discoverBoundary(br, ""); err != nil {
+			return nil, ErrNotMultipart
+		}
+	}
+	return p.mr.nested(p, boundary, base), nil
+}
+
+// nested returns a Reader of the parts in r, a body read from mr, that
+// inherits the settings and limits of mr. base is the offset of r in
+// the input of mr.
+func (mr *Reader) nested(r io.Reader, boundary string, base int64) *Reader {
+	nr := newReader(r, boundary, mr.correctUTF8qp)
+	nr.repairs = mr.repairs
+	nr.base = base
+	nr.opts = mr.opts
+	nr.fileNameMode = mr.fileNameMode
+	nr.ctx = mr.ctx
+	nr.noDrain = mr.noDrain
+	nr.limits = mr.limits
+	nr.depth = mr.depth + 1
+	nr.SetRecovery(mr.recover)
+	return nr
+}
+
+// checkDepth fails if a Reader nested in mr would exceed MaxDepth.
+func (mr *Reader) checkDepth() error {
+	if max := mr.opts.MaxDepth; max > 0 && mr.depth >= max {
+		return mr.stop(&LimitError{Limit: "MaxDepth", Max: int64(max)})
+	}
+	return nil
+}
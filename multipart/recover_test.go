@@ -0,0 +1,107 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	body := strings.Join([]string{
+		"preamble",
+		"--frontier",
+		"Content-Type: text/plain",
+		"",
+		"first",
+		"frontier",
+		"Content-Type: text/plain",
+		"",
+		"second\r",
+		"--frontier",
+		"Content-Type: multipart/alternative",
+		"",
+		"no boundary here",
+		"--frontier",
+		"",
+		"truncated",
+	}, "\r\n")
+	// the second part ends with a bare LF before the delimiter
+	body = strings.Replace(body, "second\r\r\n", "second\n", 1)
+
+	strict := NewReader(strings.NewReader(body), `"frontier" `)
+	if _, err := strict.NextPart(); err == nil {
+		t.Fatal("strict reader accepted a quoted boundary")
+	}
+
+	r := NewReader(strings.NewReader(body), `"frontier" `)
+	r.SetRecovery(true)
+	var got []string
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart after %q: %v", got, err)
+		}
+		if _, err := p.MultipartReader(); err != ErrNotMultipart {
+			t.Errorf("MultipartReader() = %v; want ErrNotMultipart", err)
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		got = append(got, string(b))
+	}
+	want := []string{"first", "second", "no boundary here", "truncated"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parts = %q; want %q", got, want)
+	}
+
+	var kinds []RepairKind
+	for _, rp := range r.Repairs() {
+		kinds = append(kinds, rp.Kind)
+	}
+	wantKinds := []RepairKind{
+		RepairBoundaryParameter,
+		RepairBoundaryWithoutDashes,
+		RepairMissingNestedBoundary,
+		RepairMissingFinalBoundary,
+	}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("repairs = %v; want %v", kinds, wantKinds)
+	}
+}
+
+func TestRecoverySkipsUnexpectedLines(t *testing.T) {
+	body := "junk after a part\r\n\r\nmore junk\r\n--b\r\n\r\nbody\r\n--b--\r\n"
+	r := NewReader(strings.NewReader(body), "b")
+	r.partsRead = 1 // as if positioned after a part
+	if _, err := r.NextPart(); err == nil {
+		t.Fatal("strict reader accepted unexpected lines")
+	}
+
+	r = NewReader(strings.NewReader(body), "b")
+	r.partsRead = 1
+	r.SetRecovery(true)
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if b, _ := ioutil.ReadAll(p); string(b) != "body" {
+		t.Errorf("body = %q", b)
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Errorf("final NextPart = %v; want io.EOF", err)
+	}
+	if rs := r.Repairs(); len(rs) != 2 || rs[0].Kind != RepairSkippedLine ||
+		rs[0].Line != "junk after a part\r\n" || rs[1].Line != "more junk\r\n" {
+		t.Errorf("repairs = %+v", rs)
+	}
+}
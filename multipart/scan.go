@@ -0,0 +1,51 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import "bytes"
+
+// minSkipSeparator is the shortest separator searched with skipping;
+// bytes.Index is faster for shorter ones.
+const minSkipSeparator = 16
+
+// A sepFinder finds a separator with the Boyer-Moore-Horspool algorithm.
+// Body bytes that cannot end a match are skipped unexamined, so with a
+// typical boundary of tens of bytes most of a body is never looked at,
+// where bytes.Index stops at every line break.
+type sepFinder struct {
+	sep  []byte
+	skip [256]int // shift when a byte ends the window
+}
+
+func (f *sepFinder) reset(sep []byte) {
+	f.sep = sep
+	if len(sep) < minSkipSeparator {
+		return
+	}
+	last := len(sep) - 1
+	for i := range f.skip {
+		f.skip[i] = len(sep)
+	}
+	for i := 0; i < last; i++ {
+		f.skip[sep[i]] = last - i
+	}
+}
+
+// index returns the index of the first separator in b, or -1.
+func (f *sepFinder) index(b []byte) int {
+	sep := f.sep
+	if len(sep) < minSkipSeparator {
+		return bytes.Index(b, sep)
+	}
+	last := len(sep) - 1
+	for i := 0; i+last < len(b); {
+		c := b[i+last]
+		if c == sep[last] && bytes.Equal(b[i:i+last], sep[:last]) {
+			return i
+		}
+		i += f.skip[c]
+	}
+	return -1
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestSepFinder(t *testing.T) {
+	seps := []string{"\r\n--b", "\r\n--" + strings.Repeat("ab", 20), "\n--" + strings.Repeat("ab", 20)}
+	for _, sep := range seps {
+		var f sepFinder
+		f.reset([]byte(sep))
+		for _, body := range []string{
+			"",
+			sep,
+			"x" + sep,
+			sep[:len(sep)-1] + "x" + sep + "tail",
+			strings.Repeat("\r\n--aba", 50) + sep,
+			strings.Repeat("b", 100) + sep[1:],
+		} {
+			if g, e := f.index([]byte(body)), bytes.Index([]byte(body), []byte(sep)); g != e {
+				t.Errorf("index(%q) in %q = %d; want %d", sep, body, g, e)
+			}
+		}
+	}
+}
+
+func TestSetBufferSize(t *testing.T) {
+	boundary := strings.Repeat("0123456789", 7)
+	var parts []string
+	var body bytes.Buffer
+	for n := 0; n < 600; n += 37 {
+		s := strings.Repeat("x\r\n-", n/4) + strings.Repeat("y", n%4)
+		parts = append(parts, s)
+		body.WriteString("--" + boundary + "\r\n\r\n" + s + "\r\n")
+	}
+	body.WriteString("--" + boundary + "--\r\n")
+
+	r := NewReader(&body, boundary)
+	if err := r.SetBufferSize(1); err != nil {
+		t.Fatal(err)
+	}
+	if r.bufSize != minBufferSize {
+		t.Errorf("buffer size = %d; want %d", r.bufSize, minBufferSize)
+	}
+	for i, want := range parts {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if b, _ := ioutil.ReadAll(p); string(b) != want {
+			t.Errorf("part %d = %q; want %q", i, b, want)
+		}
+	}
+	if err := r.SetBufferSize(8192); err == nil {
+		t.Error("SetBufferSize after reading succeeded")
+	}
+}
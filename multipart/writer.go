@@ -11,8 +11,11 @@ import (
 	"fmt"
 	"io"
 	//"net/textproto"
+	"sort"
 	"strings"
 
+	"github.com/cention-sany/mime"
+	"github.com/cention-sany/mime/quotedprintable"
 	"github.com/cention-sany/net/textproto"
 )
 
@@ -21,15 +24,118 @@ type Writer struct {
 	w        io.Writer
 	boundary string
 	lastpart *part
+
+	parent *Writer // writer this one is nested in, if any
+	child  *Writer // open nested writer created by CreateMultipart
+	nested bool    // created by CreateMultipart, boundary already written
+
+	check BoundaryCheck
+	order []string // header keys written first, in this order
+	fold  int      // fold header lines longer than this, if > 0
+	nl    string   // line ending, "\r\n" unless set by SetLineEnding
+
+	policy HeaderPolicy
+
+	preamble string // written before the first delimiter, then cleared
+	epilogue string
 }
 
 // NewWriter returns a new multipart Writer with a random boundary,
 // writing to w.
+//
+// If w is a part of another Writer, the new Writer is nested in it: its
+// boundary never collides with the boundaries of the enclosing writers
+// and it inherits their BoundaryCheck.
 func NewWriter(w io.Writer) *Writer {
-	return &Writer{
-		w:        w,
-		boundary: randomBoundary(),
+	mw := &Writer{w: w, nl: "\r\n"}
+	if p, ok := w.(*part); ok {
+		mw.inherit(p.mw)
+	}
+	mw.boundary = randomBoundary()
+	for mw.parent.collides(mw.boundary) {
+		mw.boundary = randomBoundary()
 	}
+	return mw
+}
+
+// inherit nests w in parent and copies its settings.
+func (w *Writer) inherit(parent *Writer) {
+	w.parent = parent
+	w.check = parent.check
+	w.order = parent.order
+	w.fold = parent.fold
+	w.nl = parent.nl
+	w.policy = parent.policy
+}
+
+// SetHeaderOrder sets the order in which part header fields are
+// written: the given keys first, in that order, then the remaining
+// keys sorted. Without it all keys are written sorted, so the output
+// does not depend on map iteration order. Writers nested in w that are
+// created afterwards inherit the order.
+func (w *Writer) SetHeaderOrder(keys ...string) {
+	w.order = make([]string, len(keys))
+	for i, k := range keys {
+		w.order[i] = textproto.CanonicalMIMEHeaderKey(k)
+	}
+}
+
+// SetHeaderFolding makes the Writer fold part header lines longer than
+// width characters at whitespace, as described in RFC 5322 section
+// 2.2.3. A width of 0, the default, disables folding. Writers nested
+// in w that are created afterwards inherit the setting.
+func (w *Writer) SetHeaderFolding(width int) {
+	w.fold = width
+}
+
+// SetLineEnding sets the line ending written after boundary delimiters
+// and header lines to "\r\n", the default required by RFC 2046, or to
+// "\n" for output that is converted by a later stage, such as a local
+// mailbox. It must be called before any parts are created. Part
+// content is written unchanged.
+func (w *Writer) SetLineEnding(nl string) error {
+	if w.lastpart != nil || w.nested {
+		return errors.New("mime: SetLineEnding called after write")
+	}
+	if nl != "\r\n" && nl != "\n" {
+		return errors.New(`mime: line ending must be "\r\n" or "\n"`)
+	}
+	w.nl = nl
+	return nil
+}
+
+// BoundaryCheck selects how a Writer guards against part content that
+// contains its boundary delimiter, which would silently corrupt the
+// output.
+type BoundaryCheck int
+
+const (
+	// BoundaryCheckOff writes part content unchecked. This is the
+	// default and is safe with the random boundaries of NewWriter.
+	BoundaryCheckOff BoundaryCheck = iota
+
+	// BoundaryCheckError makes writes to a part fail with
+	// ErrBoundaryCollision when the content contains the delimiter,
+	// even when it is split across Write calls.
+	BoundaryCheckError
+
+	// BoundaryCheckReencode buffers the content of each part until
+	// the part is finished and, if it contains the delimiter, writes
+	// it base64 encoded instead, setting Content-Transfer-Encoding.
+	// Multipart parts created by CreateMultipart are not buffered;
+	// their nested writer checks its own parts.
+	BoundaryCheckReencode
+)
+
+// ErrBoundaryCollision is returned by writes to a part whose content
+// contains the boundary delimiter of its Writer.
+var ErrBoundaryCollision = errors.New("multipart: part content contains the boundary delimiter")
+
+// SetBoundaryCheck sets how the content of parts created afterwards is
+// checked for the boundary delimiter. Writers nested in w that are
+// created afterwards inherit the setting.
+func (w *Writer) SetBoundaryCheck(check BoundaryCheck) {
+	w.check = check
 }
 
 // Boundary returns the Writer's boundary.
@@ -47,6 +153,9 @@ func (w *Writer) SetBoundary(boundary string) error {
 	if w.lastpart != nil {
 		return errors.New("mime: SetBoundary called after write")
 	}
+	if w.nested {
+		return errors.New("mime: SetBoundary called on nested writer")
+	}
 	// rfc2046#section-5.1.1
 	if len(boundary) < 1 || len(boundary) > 69 {
 		return errors.New("mime: invalid boundary length")
@@ -61,6 +170,9 @@ func (w *Writer) SetBoundary(boundary string) error {
 		}
 		return errors.New("mime: invalid boundary character")
 	}
+	if w.parent.collides(boundary) {
+		return errors.New("mime: boundary collides with an enclosing boundary")
+	}
 	w.boundary = boundary
 	return nil
 }
@@ -84,7 +196,23 @@ func randomBoundary() string {
 // header. The body of the part should be written to the returned
 // Writer. After calling CreatePart, any previous part may no longer
 // be written to.
+//
+// Header field names must be valid RFC 5322 field names and values
+// may not contain line breaks or other control characters; otherwise
+// a *HeaderError is returned and nothing is written. Values with 8-bit
+// bytes are handled as set by SetHeaderPolicy.
 func (w *Writer) CreatePart(header textproto.MIMEHeader) (io.Writer, error) {
+	return w.createPart(header, w.check == BoundaryCheckReencode)
+}
+
+func (w *Writer) createPart(header textproto.MIMEHeader, buffered bool) (*part, error) {
+	if w.child != nil {
+		return nil, errNestedOpen
+	}
+	header, err := checkHeader(header, w.policy)
+	if err != nil {
+		return nil, err
+	}
 	if w.lastpart != nil {
 		if err := w.lastpart.close(); err != nil {
 			return nil, err
@@ -92,27 +220,153 @@ func (w *Writer) CreatePart(header textproto.MIMEHeader) (io.Writer, error) {
 	}
 	var b bytes.Buffer
 	if w.lastpart != nil {
-		fmt.Fprintf(&b, "\r\n--%s\r\n", w.boundary)
+		fmt.Fprintf(&b, "%s--%s%s", w.nl, w.boundary, w.nl)
 	} else {
-		fmt.Fprintf(&b, "--%s\r\n", w.boundary)
+		w.writePreamble(&b)
+		fmt.Fprintf(&b, "--%s%s", w.boundary, w.nl)
 	}
-	// TODO(bradfitz): move this to textproto.MimeHeader.Write(w), have it sort
-	// and clean, like http.Header.Write(w) does.
-	for k, vv := range header {
-		for _, v := range vv {
-			fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+	p := &part{
+		mw: w,
+	}
+	if w.check != BoundaryCheckOff {
+		p.scan = newDelimScanner(w.boundary)
+	}
+	if buffered {
+		// the header is written on close, once the content is known
+		p.header = header
+		p.buf = new(bytes.Buffer)
+	} else {
+		w.writeHeader(&b, header)
+	}
+	if _, err := io.Copy(w.w, &b); err != nil {
+		return nil, err
+	}
+	w.lastpart = p
+	return p, nil
+}
+
+// writeHeader writes header and the blank line ending it to b using
+// the Writer's header order, folding and line ending.
+func (w *Writer) writeHeader(b *bytes.Buffer, header textproto.MIMEHeader) {
+	writeHeaderLines(b, header, w.order, w.fold, w.nl)
+}
+
+func writeHeaderLines(b *bytes.Buffer, header textproto.MIMEHeader, order []string, fold int, nl string) {
+	for _, k := range headerKeys(header, order) {
+		for _, v := range header[k] {
+			b.WriteString(k)
+			b.WriteString(": ")
+			foldValue(b, len(k)+2, v, fold, nl)
+			b.WriteString(nl)
 		}
 	}
-	fmt.Fprintf(&b, "\r\n")
-	_, err := io.Copy(w.w, &b)
+	b.WriteString(nl)
+}
+
+// headerKeys returns the keys of header, those in order first and the
+// rest sorted.
+func headerKeys(header textproto.MIMEHeader, order []string) []string {
+	keys := make([]string, 0, len(header))
+	seen := make(map[string]bool, len(order))
+	for _, k := range order {
+		if _, ok := header[k]; ok && !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+	rest := len(keys)
+	for k := range header {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[rest:])
+	return keys
+}
+
+// foldValue writes v, which starts at column col, breaking it before
+// whitespace so that lines stay within width where possible.
+func foldValue(b *bytes.Buffer, col int, v string, width int, nl string) {
+	if width <= 0 || col+len(v) <= width {
+		b.WriteString(v)
+		return
+	}
+	start := true // nothing but the field name on the line yet
+	for len(v) > 0 {
+		// a chunk is leading whitespace followed by a word
+		i := 0
+		for i < len(v) && (v[i] == ' ' || v[i] == '\t') {
+			i++
+		}
+		for i < len(v) && v[i] != ' ' && v[i] != '\t' {
+			i++
+		}
+		chunk := v[:i]
+		v = v[i:]
+		if !start && col+len(chunk) > width && (chunk[0] == ' ' || chunk[0] == '\t') {
+			b.WriteString(nl)
+			col = 0
+		}
+		b.WriteString(chunk)
+		col += len(chunk)
+		start = false
+	}
+}
+
+var errNestedOpen = errors.New("multipart: nested multipart writer not closed")
+
+// CreateMultipart creates a new part whose body is itself a
+// multipart/subtype body, and returns the Writer for that body. The
+// Content-Type of header, if it is multipart, keeps its parameters but
+// gets the boundary of the new Writer, which never collides with the
+// boundary of w or of the writers w is nested in.
+//
+// The returned Writer must be closed before w creates another part or
+// is closed.
+func (w *Writer) CreateMultipart(subtype string, header textproto.MIMEHeader) (*Writer, error) {
+	if w.child != nil {
+		return nil, errNestedOpen
+	}
+	params := make(map[string]string)
+	if v := header.Get("Content-Type"); v != "" {
+		if typ, p, err := parseMediaType(v); err == nil && strings.HasPrefix(typ, "multipart/") {
+			params = p
+		}
+	}
+	child := &Writer{nested: true}
+	child.inherit(w)
+	for child.boundary = randomBoundary(); w.collides(child.boundary); {
+		child.boundary = randomBoundary()
+	}
+	params["boundary"] = child.boundary
+	ct := mime.FormatMediaType("multipart/"+subtype, params)
+	if ct == "" {
+		return nil, fmt.Errorf("multipart: invalid multipart subtype %q", subtype)
+	}
+	h := make(textproto.MIMEHeader, len(header)+1)
+	for k, vv := range header {
+		h[k] = vv
+	}
+	h.Set("Content-Type", ct)
+	pw, err := w.createPart(h, false)
 	if err != nil {
 		return nil, err
 	}
-	p := &part{
-		mw: w,
+	child.w = pw
+	w.child = child
+	return child, nil
+}
+
+// collides reports whether boundary could be mistaken for the boundary
+// of w or of any writer w is nested in, that is whether either is a
+// prefix of the other. A nil w collides with nothing.
+func (w *Writer) collides(boundary string) bool {
+	for ; w != nil; w = w.parent {
+		if strings.HasPrefix(boundary, w.boundary) || strings.HasPrefix(w.boundary, boundary) {
+			return true
+		}
 	}
-	w.lastpart = p
-	return p, nil
+	return false
 }
 
 var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
@@ -154,13 +408,23 @@ func (w *Writer) WriteField(fieldname, value string) error {
 // Close finishes the multipart message and writes the trailing
 // boundary end line to the output.
 func (w *Writer) Close() error {
+	if w.child != nil {
+		return errNestedOpen
+	}
+	var b bytes.Buffer
 	if w.lastpart != nil {
 		if err := w.lastpart.close(); err != nil {
 			return err
 		}
 		w.lastpart = nil
+	} else {
+		w.writePreamble(&b)
+	}
+	fmt.Fprintf(&b, "%s--%s--%s%s", w.nl, w.boundary, w.nl, w.epilogue)
+	_, err := b.WriteTo(w.w)
+	if w.parent != nil && w.parent.child == w {
+		w.parent.child = nil
 	}
-	_, err := fmt.Fprintf(w.w, "\r\n--%s--\r\n", w.boundary)
 	return err
 }
 
@@ -168,20 +432,104 @@ type part struct {
 	mw     *Writer
 	closed bool
 	we     error // last error that occurred writing
+
+	scan   *delimScanner        // non-nil when checking for the delimiter
+	header textproto.MIMEHeader // header of a buffered part
+	buf    *bytes.Buffer        // content of a buffered part
+	found  bool                 // buffered content contains the delimiter
 }
 
 func (p *part) close() error {
+	if p.closed {
+		return p.we
+	}
 	p.closed = true
+	if p.buf != nil && p.we == nil {
+		p.we = p.flush()
+	}
 	return p.we
 }
 
+// flush writes the header and content of a buffered part, base64
+// encoding the content if it contains the delimiter.
+func (p *part) flush() error {
+	var b bytes.Buffer
+	var body io.Reader = p.buf
+	if p.found {
+		h := make(textproto.MIMEHeader, len(p.header)+1)
+		for k, vv := range p.header {
+			h[k] = vv
+		}
+		const cte = "Content-Transfer-Encoding"
+		if strings.EqualFold(h.Get(cte), "quoted-printable") {
+			body = quotedprintable.NewReader(body)
+		}
+		h.Set(cte, "base64")
+		p.header = h
+	}
+	p.mw.writeHeader(&b, p.header)
+	if _, err := b.WriteTo(p.mw.w); err != nil {
+		return err
+	}
+	if p.found {
+		return writeEncoded(p.mw.w, "base64", body, p.mw.nl)
+	}
+	_, err := p.buf.WriteTo(p.mw.w)
+	return err
+}
+
 func (p *part) Write(d []byte) (n int, err error) {
 	if p.closed {
 		return 0, errors.New("multipart: can't write to finished part")
 	}
+	if p.buf != nil {
+		if !p.found {
+			p.found = p.scan.scan(d)
+		}
+		return p.buf.Write(d)
+	}
+	if p.scan != nil && p.scan.scan(d) {
+		p.we = ErrBoundaryCollision
+		return 0, p.we
+	}
 	n, err = p.mw.w.Write(d)
 	if err != nil {
 		p.we = err
 	}
 	return
 }
+
+// delimScanner looks for a boundary delimiter at the start of a line in
+// content given to it in pieces.
+type delimScanner struct {
+	delim []byte // "\n--boundary"
+	tail  []byte // last len(delim)-1 bytes scanned
+}
+
+func newDelimScanner(boundary string) *delimScanner {
+	// content starts at the beginning of a line
+	return &delimScanner{delim: []byte("\n--" + boundary), tail: []byte("\n")}
+}
+
+// scan reports whether the delimiter appears in the content scanned so
+// far, ending with p.
+func (s *delimScanner) scan(p []byte) bool {
+	keep := len(s.delim) - 1
+	seam := p
+	if len(seam) > keep {
+		seam = seam[:keep]
+	}
+	joined := append(s.tail, seam...)
+	if bytes.Contains(joined, s.delim) || bytes.Contains(p, s.delim) {
+		return true
+	}
+	if len(p) >= keep {
+		s.tail = append(s.tail[:0], p[len(p)-keep:]...)
+	} else {
+		if len(joined) > keep {
+			joined = joined[len(joined)-keep:]
+		}
+		s.tail = append([]byte(nil), joined...)
+	}
+	return false
+}
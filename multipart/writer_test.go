@@ -0,0 +1,291 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cention-sany/net/textproto"
+)
+
+func TestCreateMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", `multipart/related; type="text/html"`)
+	cw, err := w.CreateMultipart("related", h)
+	if err != nil {
+		t.Fatalf("CreateMultipart: %v", err)
+	}
+	if cw.Boundary() == w.Boundary() {
+		t.Error("nested writer reuses parent boundary")
+	}
+	if err := cw.SetBoundary("other"); err == nil {
+		t.Error("SetBoundary on nested writer succeeded")
+	}
+	if err := cw.WriteField("inner", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.CreatePart(nil); err != errNestedOpen {
+		t.Errorf("parent CreatePart with open child = %v; want errNestedOpen", err)
+	}
+	if err := w.Close(); err != errNestedOpen {
+		t.Errorf("parent Close with open child = %v; want errNestedOpen", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteField("outer", "value2"); err != nil {
+		t.Fatalf("parent WriteField after child Close: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(&buf, w.Boundary())
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	typ, params, err := parseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || typ != "multipart/related" || params["type"] != "text/html" ||
+		params["boundary"] != cw.Boundary() {
+		t.Fatalf("nested Content-Type = %q", p.Header.Get("Content-Type"))
+	}
+	ip, err := NewReader(p, params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatalf("inner NextPart: %v", err)
+	}
+	if b, _ := ioutil.ReadAll(ip); string(b) != "value" || ip.FormName() != "inner" {
+		t.Errorf("inner part = %q %q", ip.FormName(), b)
+	}
+	p, err = r.NextPart()
+	if err != nil {
+		t.Fatalf("second NextPart: %v", err)
+	}
+	if b, _ := ioutil.ReadAll(p); string(b) != "value2" || p.FormName() != "outer" {
+		t.Errorf("outer part = %q %q", p.FormName(), b)
+	}
+}
+
+func TestWriterCollides(t *testing.T) {
+	w := NewWriter(nil)
+	w.SetBoundary("abc")
+	child := &Writer{parent: w, boundary: "xyz"}
+	for _, b := range []string{"abc", "abcd", "ab", "xyz1"} {
+		if !child.collides(b) {
+			t.Errorf("collides(%q) = false", b)
+		}
+	}
+	if child.collides("def") {
+		t.Error("collides(def) = true")
+	}
+}
+
+func TestBoundaryCheckError(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetBoundary("b")
+	w.SetBoundaryCheck(BoundaryCheckError)
+	p, err := w.CreateFormField("f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write([]byte("fine -- b\r\n-")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// the delimiter is split across writes
+	if _, err := p.Write([]byte("-b\r\n")); err != ErrBoundaryCollision {
+		t.Errorf("Write = %v; want ErrBoundaryCollision", err)
+	}
+	if err := w.Close(); err != ErrBoundaryCollision {
+		t.Errorf("Close = %v; want ErrBoundaryCollision", err)
+	}
+
+	p, _ = NewWriter(&buf).CreatePart(nil)
+	if _, err := p.Write([]byte("--b")); err != nil {
+		t.Errorf("unchecked Write = %v", err)
+	}
+}
+
+func TestBoundaryCheckReencode(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetBoundary("b")
+	w.SetBoundaryCheck(BoundaryCheckReencode)
+	const bad = "--b\r\nfake part\r\n--b--"
+	if err := w.WriteField("bad", bad); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteField("good", "plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(&buf, "b")
+	for _, want := range []struct{ name, cte, body string }{
+		{"bad", "base64", bad},
+		{"good", "", "plain"},
+	} {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		b, _ := ioutil.ReadAll(p)
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			b, _ = base64.StdEncoding.DecodeString(string(b))
+		}
+		if p.FormName() != want.name || p.Header.Get("Content-Transfer-Encoding") != want.cte ||
+			string(b) != want.body {
+			t.Errorf("part %q: header %v, body %q", p.FormName(), p.Header, b)
+		}
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Errorf("final NextPart = %v; want io.EOF", err)
+	}
+}
+
+func TestNestedNewWriter(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+	w.SetBoundary("outer")
+	w.SetBoundaryCheck(BoundaryCheckError)
+	p, _ := w.CreatePart(nil)
+	nw := NewWriter(p)
+	if nw.check != BoundaryCheckError {
+		t.Error("nested writer did not inherit BoundaryCheck")
+	}
+	if err := nw.SetBoundary("outer2"); err == nil {
+		t.Error("SetBoundary accepted a boundary extending the parent's")
+	}
+	if err := nw.SetBoundary("inner"); err != nil {
+		t.Errorf("SetBoundary(inner) = %v", err)
+	}
+}
+
+func TestWriterHeaderOrder(t *testing.T) {
+	h := textproto.MIMEHeader{
+		"Content-Type":        {"text/plain"},
+		"Content-Disposition": {`form-data; name="a"`},
+		"X-B":                 {"2"},
+		"X-A":                 {"1"},
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetBoundary("b")
+	w.CreatePart(h)
+	w.SetHeaderOrder("content-disposition", "X-B", "Missing")
+	w.CreatePart(h)
+	w.Close()
+	want := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"a\"\r\nContent-Type: text/plain\r\nX-A: 1\r\nX-B: 2\r\n\r\n" +
+		"\r\n--b\r\n" +
+		"Content-Disposition: form-data; name=\"a\"\r\nX-B: 2\r\nContent-Type: text/plain\r\nX-A: 1\r\n\r\n" +
+		"\r\n--b--\r\n"
+	if g := buf.String(); g != want {
+		t.Errorf("got:\n%q\nwant:\n%q", g, want)
+	}
+}
+
+func TestWriterFoldingAndLF(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetBoundary("b")
+	if err := w.SetLineEnding("\r"); err == nil {
+		t.Error("SetLineEnding accepted a bare CR")
+	}
+	if err := w.SetLineEnding("\n"); err != nil {
+		t.Fatal(err)
+	}
+	w.SetHeaderFolding(20)
+	h := textproto.MIMEHeader{"X-Long": {"aaaa bbbb cccc dddddddddddddddddddd e"}}
+	p, _ := w.CreatePart(h)
+	p.Write([]byte("body"))
+	w.Close()
+	want := "--b\nX-Long: aaaa bbbb\n cccc\n dddddddddddddddddddd\n e\n\nbody\n--b--\n"
+	if g := buf.String(); g != want {
+		t.Errorf("got:\n%q\nwant:\n%q", g, want)
+	}
+
+	rp, err := NewReader(&buf, "b").NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if g, e := rp.Header.Get("X-Long"), "aaaa bbbb cccc dddddddddddddddddddd e"; g != e {
+		t.Errorf("unfolded header = %q; want %q", g, e)
+	}
+}
+
+func TestCreatePartHeaderValidation(t *testing.T) {
+	bad := []textproto.MIMEHeader{
+		{"X-Evil": {"a\r\nInjected: yes"}},
+		{"X-Evil": {"a\n--boundary"}},
+		{"X-Evil": {"nul\x00"}},
+		{"Bad Name": {"v"}},
+		{"Bad:Name": {"v"}},
+		{"X-Latin1": {"caf\xe9"}},
+	}
+	for _, h := range bad {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		_, err := w.CreatePart(h)
+		if _, ok := err.(*HeaderError); !ok {
+			t.Errorf("CreatePart(%q) = %v; want *HeaderError", h, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("CreatePart(%q) wrote %q", h, buf.String())
+		}
+	}
+	if _, err := NewWriter(ioutil.Discard).CreateFormFile("f", "a\r\nb"); err == nil {
+		t.Error("CreateFormFile accepted a filename with CRLF")
+	}
+
+	tests := []struct {
+		policy     HeaderPolicy
+		key, value string
+		want       string // "" for an error
+	}{
+		{HeaderAllowUTF8, "Subject", "café", "café"},
+		{HeaderASCIIOnly, "Subject", "café", ""},
+		{HeaderEncodeWords, "Subject", "café", "=?utf-8?q?caf=C3=A9?="},
+		{HeaderEncodeWords, "Content-Disposition", `form-data; name="f"; filename="é.txt"`,
+			`form-data; name="f"; filename*=utf-8''%C3%A9.txt`},
+		{HeaderEncodeWords, "Content-Disposition", `form-data; name="a\"; x=\"y"; filename="日本.txt"`,
+			`form-data; name="a\"; x=\"y"; filename*=utf-8''%E6%97%A5%E6%9C%AC.txt`},
+		{HeaderEncodeWords, "Content-Disposition", `form-data; name="a\"日\"b"`,
+			`form-data; name*=utf-8''a%22%E6%97%A5%22b`},
+		{HeaderEncodeWords, "Content-Disposition", `form-data; name=é`, ""},
+		{HeaderEncodeWords, "Content-Disposition", `form-data; "日"`, ""},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetHeaderPolicy(tt.policy)
+		_, err := w.CreatePart(textproto.MIMEHeader{tt.key: {tt.value}})
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("policy %d: %s: %q accepted", tt.policy, tt.key, tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("policy %d: %s: %q: %v", tt.policy, tt.key, tt.value, err)
+			continue
+		}
+		if line := tt.key + ": " + tt.want + "\r\n"; !bytes.Contains(buf.Bytes(), []byte(line)) {
+			t.Errorf("policy %d: wrote %q; want line %q", tt.policy, buf.String(), line)
+		}
+	}
+	// an escaped quote next to an encoded parameter adds no parameter
+	_, params, err := parseMediaType(`form-data; name="a\"; x=\"y"; filename*=utf-8''%E6%97%A5%E6%9C%AC.txt`)
+	if err != nil || len(params) != 2 || params["name"] != `a"; x="y` || params["filename"] != "日本.txt" {
+		t.Errorf("parameters %q, %v", params, err)
+	}
+}